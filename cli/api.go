@@ -0,0 +1,212 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathControlAddress = "cli.controladdress"
+	PathControlPaused  = "cli.controlpaused"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathControlAddress, "", "address (e.g. localhost:8070) to serve the control-API on (disabled if empty)")
+	config.Viper.BindPFlag(PathControlAddress, config.RootCtx.PersistentFlags().Lookup(PathControlAddress))
+
+	config.RootCtx.PersistentFlags().Bool(PathControlPaused, false, "start paused, waiting for the control-API to resume or step")
+	config.Viper.BindPFlag(PathControlPaused, config.RootCtx.PersistentFlags().Lookup(PathControlPaused))
+}
+
+// progress is shared between the feeding loop and the control-API.
+type progress struct {
+	m          sync.Mutex
+	date       time.Time
+	steps      uint
+	production uint
+	weather    uint
+}
+
+func (p *progress) step(date time.Time) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.date = date
+	p.steps++
+}
+
+func (p *progress) wrote(production, weather uint) {
+	p.m.Lock()
+	defer p.m.Unlock()
+	p.production += production
+	p.weather += weather
+}
+
+// remoteController is controlled via HTTP. Only if it is not paused (or has
+// remaining single steps) the wrapped controller is asked.
+type remoteController struct {
+	base     controller
+	progress *progress
+	m        sync.Mutex
+	cond     *sync.Cond
+	paused   bool
+	waiting  bool
+	steps    uint
+	seek     *time.Time
+}
+
+// status is the control-API's response body.
+type status struct {
+	Paused     bool      `json:"paused"`
+	Waiting    bool      `json:"waiting"`
+	Date       time.Time `json:"date"`
+	Unix       int64     `json:"unix"`
+	Steps      uint      `json:"steps"`
+	Production uint      `json:"production"`
+	Weather    uint      `json:"weather"`
+}
+
+// newRemoteController listens on address before returning, so the control-API
+// is reachable as soon as the run starts.
+func newRemoteController(base controller, p *progress, address string, paused bool) (*remoteController, error) {
+	c := &remoteController{
+		base:     base,
+		progress: p,
+		paused:   paused,
+	}
+	c.cond = sync.NewCond(&c.m)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", c.handle(http.MethodGet, func(r *http.Request) error {
+		return nil
+	}))
+	mux.HandleFunc("/pause", c.handle(http.MethodPost, func(r *http.Request) error {
+		c.paused = true
+		c.steps = 0
+		return nil
+	}))
+	mux.HandleFunc("/resume", c.handle(http.MethodPost, func(r *http.Request) error {
+		c.paused = false
+		return nil
+	}))
+	mux.HandleFunc("/step", c.handle(http.MethodPost, func(r *http.Request) error {
+		n := uint64(1)
+		if v := r.URL.Query().Get("n"); v != "" {
+			var err error
+			if n, err = strconv.ParseUint(v, 10, 64); err != nil {
+				return err
+			}
+		}
+		c.paused = true
+		c.steps += uint(n)
+		return nil
+	}))
+	mux.HandleFunc("/seek", c.handle(http.MethodPost, func(r *http.Request) error {
+		t, err := parseTime(r.URL.Query().Get("time"))
+		if err != nil {
+			return err
+		}
+		c.seek = &t
+		return nil
+	}))
+
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		log.WithField("address", address).Info("serving control-API")
+		if err := http.Serve(l, mux); err != nil {
+			log.WithError(err).Error("control-API failed")
+		}
+	}()
+	return c, nil
+}
+
+// handle wraps fn, which is called while holding the controller's lock. It
+// responds with the current status.
+func (c *remoteController) handle(method string, fn func(r *http.Request) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		c.m.Lock()
+		err := fn(r)
+		c.cond.Broadcast()
+		s := status{
+			Paused:  c.paused,
+			Waiting: c.waiting,
+		}
+		c.m.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		c.progress.m.Lock()
+		s.Date = c.progress.date
+		s.Unix = c.progress.date.Unix()
+		s.Steps = c.progress.steps
+		s.Production = c.progress.production
+		s.Weather = c.progress.weather
+		c.progress.m.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(s)
+	}
+}
+
+func (c *remoteController) wait(date time.Time) *time.Time {
+	c.m.Lock()
+	if c.seek == nil && c.paused && c.steps == 0 {
+		// the base may not keep running while paused, e.g. the mock-time
+		// must not advance
+		c.m.Unlock()
+		c.reset()
+		c.m.Lock()
+	}
+	c.waiting = true
+	for c.seek == nil && c.paused && c.steps == 0 {
+		c.cond.Wait()
+	}
+	c.waiting = false
+	if seek := c.seek; seek != nil {
+		c.seek = nil
+		c.m.Unlock()
+		c.reset()
+		return seek
+	}
+	if c.paused {
+		c.steps--
+	}
+	c.m.Unlock()
+	return c.base.wait(date)
+}
+
+// reset notifies the base, that execution does not continue with the next
+// time-step right away.
+func (c *remoteController) reset() {
+	if r, ok := c.base.(resetter); ok {
+		r.reset()
+	}
+}
+
+// parseTime accepts unix-timestamps (in seconds) as well as RFC3339.
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing time")
+	}
+	if unix, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(unix, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
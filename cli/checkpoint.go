@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+	"github.com/theMomax/openefs-csv-feeder/mocktime"
+	"github.com/theMomax/openefs-csv-feeder/writer"
+)
+
+// Config paths
+const (
+	PathCheckpoint = "cli.checkpoint"
+	PathResume     = "cli.resume"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathCheckpoint, "", "file the last fully written time-step is persisted to, e.g. 'checkpoint.json' (disabled if empty; never written during dry-runs)")
+	config.Viper.BindPFlag(PathCheckpoint, config.RootCtx.PersistentFlags().Lookup(PathCheckpoint))
+
+	config.RootCtx.PersistentFlags().Bool("resume", false, "continue right after the time-step persisted in "+PathCheckpoint+" (refused if the configuration or input-files changed)")
+	config.Viper.BindPFlag(PathResume, config.RootCtx.PersistentFlags().Lookup("resume"))
+}
+
+// checkpoint describes the progress of a run and the circumstances it was made
+// under.
+type checkpoint struct {
+	Timestamp  int64         `json:"timestamp"`
+	ConfigHash string        `json:"configHash"`
+	Inputs     []fingerprint `json:"inputs"`
+}
+
+// fingerprint identifies the state of an input-file.
+type fingerprint struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// newCheckpoint describes the current configuration and the given
+// input-files.
+func newCheckpoint(files []string) (*checkpoint, error) {
+	hash, err := configHash()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &checkpoint{
+		ConfigHash: hash,
+		Inputs:     make([]fingerprint, len(files)),
+	}
+	for i, f := range files {
+		abs, err := filepath.Abs(f)
+		if err != nil {
+			return nil, err
+		}
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		c.Inputs[i] = fingerprint{
+			Path:    abs,
+			Size:    info.Size(),
+			ModTime: info.ModTime().UTC(),
+		}
+	}
+	return c, nil
+}
+
+// configHash hashes all settings, that influence which data is sent where.
+func configHash() (string, error) {
+	b, err := json.Marshal(map[string]interface{}{
+		"reader":   config.Viper.AllSettings()["reader"],
+		"writer":   config.Viper.GetString(writer.PathAddress),
+		"mocktime": config.Viper.GetString(mocktime.PathMockTimeAddress),
+	})
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func loadCheckpoint(path string) (*checkpoint, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &checkpoint{}
+	return c, json.Unmarshal(b, c)
+}
+
+// save persists c with the given time-step. The file is replaced atomically,
+// so a crash never leaves a partial checkpoint behind.
+func (c *checkpoint) save(path string, date time.Time) error {
+	c.Timestamp = date.Unix()
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0666); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// check returns an error if c was made under other circumstances than
+// current.
+func (c *checkpoint) check(current *checkpoint) error {
+	if c.ConfigHash != current.ConfigHash {
+		return errors.New("the configuration changed since the checkpoint was made")
+	}
+	if len(c.Inputs) != len(current.Inputs) {
+		return errors.New("the set of input-files changed since the checkpoint was made")
+	}
+	for i, f := range c.Inputs {
+		g := current.Inputs[i]
+		if f.Path != g.Path {
+			return errors.New("the set of input-files changed since the checkpoint was made")
+		}
+		if f.Size != g.Size || !f.ModTime.Equal(g.ModTime) {
+			return errors.New("input-file '" + f.Path + "' changed since the checkpoint was made")
+		}
+	}
+	return nil
+}
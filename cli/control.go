@@ -0,0 +1,193 @@
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+	"github.com/theMomax/openefs-csv-feeder/mocktime"
+)
+
+// Control modes
+const (
+	interactive = "interactive"
+	continuous  = "continuous"
+	pace        = "pace"
+)
+
+// Config paths
+const (
+	PathMode = "cli.mode"
+	PathPace = "cli.pace"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathMode, interactive, "how execution is controlled (one of: "+interactive+", "+continuous+", "+pace+")")
+	config.Viper.BindPFlag(PathMode, config.RootCtx.PersistentFlags().Lookup(PathMode))
+
+	config.RootCtx.PersistentFlags().Bool(interactive, false, "pause before each batch and wait for input (shorthand for --"+PathMode+"="+interactive+")")
+	config.RootCtx.PersistentFlags().Bool(continuous, false, "never pause (shorthand for --"+PathMode+"="+continuous+")")
+
+	config.RootCtx.PersistentFlags().Float64(pace, 1, "process time-steps synchronized to the wall-clock, accelerated by the factor given as --"+pace+"=FACTOR (implies --"+PathMode+"="+pace+")")
+	// a bare --pace means real time
+	config.RootCtx.PersistentFlags().Lookup(pace).NoOptDefVal = "1"
+	config.Viper.BindPFlag(PathPace, config.RootCtx.PersistentFlags().Lookup(pace))
+}
+
+// controller decides when the next time-step may be processed.
+type controller interface {
+	// wait blocks until the time-step at date may be processed. If the
+	// controller wants execution to continue at another time-step instead, it
+	// returns that time-step.
+	wait(date time.Time) (seek *time.Time)
+}
+
+func newControllerFromConfig(updater *mocktime.Updater) controller {
+	if config.Viper.GetFloat64(mocktime.PathSpeed) > 0 {
+		log.WithField("speed", config.Viper.GetFloat64(mocktime.PathSpeed)).Info("mock-time advances continuously, " + PathMode + " is ignored")
+		return &clockController{
+			updater: updater,
+		}
+	}
+
+	mode := config.Viper.GetString(PathMode)
+	flags := config.RootCtx.PersistentFlags()
+	set := 0
+	for _, m := range [...]string{interactive, continuous, pace} {
+		if !flags.Changed(m) {
+			continue
+		}
+		// --interactive=false or --continuous=false select nothing
+		if on, err := flags.GetBool(m); err == nil && !on {
+			continue
+		}
+		mode = m
+		set++
+	}
+	if set > 1 {
+		log.Fatal("--" + interactive + ", --" + continuous + " and --" + pace + " are mutually exclusive")
+	}
+
+	switch mode {
+	case interactive:
+		return &interactiveController{
+			batchSize: config.Viper.GetUint(PathBatchSize),
+		}
+	case continuous:
+		return continuousController{}
+	case pace:
+		factor := config.Viper.GetFloat64(PathPace)
+		if factor <= 0 {
+			config.InvalidConfiguration(PathPace, "a positive factor")
+		}
+		return &paceController{
+			factor: factor,
+		}
+	default:
+		config.InvalidConfiguration(PathMode, [...]string{interactive, continuous, pace})
+		return nil
+	}
+}
+
+// resetter is implemented by controllers, which have to be notified if the
+// time-steps are not processed in order or execution is paused.
+type resetter interface {
+	reset()
+}
+
+// interactiveController pauses every batchSize steps and waits for the user
+// to enter the number of batches to be processed before pausing again.
+type interactiveController struct {
+	batchSize uint
+	skip      uint
+	count     uint
+}
+
+func (c *interactiveController) wait(date time.Time) *time.Time {
+	if c.count%c.batchSize == 0 {
+		if c.skip == 0 {
+			c.skip = pause(date)
+		} else {
+			c.skip--
+		}
+		c.count = 1
+	} else {
+		c.count++
+	}
+	return nil
+}
+
+func pause(date time.Time) uint {
+	r := bufio.NewReader(os.Stdin)
+	fmt.Printf("(%s | %d) > ", date.String(), date.Unix())
+	text, _ := r.ReadString('\n')
+	text = strings.TrimSpace(text)
+	nr, err := strconv.ParseUint(text, 10, 64)
+	if err != nil || nr == 0 {
+		return 0
+	}
+	return uint(nr) - 1
+}
+
+type continuousController struct{}
+
+func (continuousController) wait(date time.Time) *time.Time {
+	return nil
+}
+
+// paceController lets simulated time pass factor times as fast as the
+// wall-clock, starting at the first time-step.
+type paceController struct {
+	factor    float64
+	startWall time.Time
+	startDate time.Time
+}
+
+func (c *paceController) wait(date time.Time) *time.Time {
+	if c.startWall.IsZero() {
+		c.startWall = time.Now()
+		c.startDate = date
+		return nil
+	}
+	target := c.startWall.Add(time.Duration(float64(date.Sub(c.startDate)) / c.factor))
+	if d := time.Until(target); d > 0 {
+		time.Sleep(d)
+	}
+	return nil
+}
+
+func (c *paceController) reset() {
+	c.startWall = time.Time{}
+}
+
+// clockController lets each time-step be processed once the continuously
+// advancing mock-time has reached it.
+type clockController struct {
+	updater *mocktime.Updater
+	clock   *mocktime.Clock
+}
+
+func (c *clockController) wait(date time.Time) *time.Time {
+	if c.clock == nil {
+		c.clock = mocktime.NewClockFromConfig(c.updater, date)
+		if err := c.clock.Start(); err != nil {
+			log.Fatal(err)
+		}
+		return nil
+	}
+	if err := c.clock.WaitUntil(date); err != nil {
+		log.Fatal(err)
+	}
+	return nil
+}
+
+func (c *clockController) reset() {
+	if c.clock != nil {
+		c.clock.Stop()
+		c.clock = nil
+	}
+}
@@ -0,0 +1,86 @@
+package cli
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/theMomax/openefs-csv-feeder/config"
+	"github.com/theMomax/openefs-csv-feeder/mocktime"
+	"github.com/theMomax/openefs-csv-feeder/writer"
+)
+
+// Config paths
+const (
+	PathReplayDelays = "replay.delays"
+)
+
+var replayCtx = &cobra.Command{
+	Use:   "replay <file>",
+	Short: "Re-sends a session recorded using --record to openefs.",
+	Long:  `Re-sends a session recorded using --record to openefs in its original order. The requests' endpoints are derived from the current configuration.`,
+	Args:  cobra.ExactArgs(1),
+	Run:   replay,
+}
+
+func init() {
+	config.RootCtx.AddCommand(replayCtx)
+
+	replayCtx.Flags().Bool(PathReplayDelays, false, "wait for the originally recorded delay between two requests")
+	config.Viper.BindPFlag(PathReplayDelays, replayCtx.Flags().Lookup(PathReplayDelays))
+}
+
+func replay(cmd *cobra.Command, args []string) {
+	s, err := writer.NewSinkFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := writer.NewWriterFromConfig(s)
+	u := mocktime.NewUpdaterFromConfig(s)
+
+	delays := config.Viper.GetBool(PathReplayDelays)
+	var previous time.Time
+	count := 0
+
+	err = writer.ReadEntries(args[0], func(e *writer.Entry) error {
+		if delays && !previous.IsZero() && e.Recorded.After(previous) {
+			time.Sleep(e.Recorded.Sub(previous))
+		}
+		previous = e.Recorded
+
+		if !rewrite(e.Request, w, u) {
+			return nil
+		}
+		if e.Kind == writer.KindMockTime {
+			log.WithField("date", e.Time()).Info("updated mocktime")
+		}
+
+		count++
+		return s.Send(e.Request)
+	})
+	if err != nil {
+		fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		fatal(err)
+	}
+	log.WithField("amount", count).Info("completed")
+}
+
+// rewrite derives r's endpoint from the current configuration. It returns
+// false if r is of unknown kind.
+func rewrite(r *writer.Request, w *writer.Writer, u *mocktime.Updater) bool {
+	switch r.Kind {
+	case writer.KindMockTime:
+		r.Endpoint = u.Endpoint(r.Time())
+	case writer.KindProduction, writer.KindWeather:
+		if r.Batch {
+			r.Endpoint = w.BatchEndpoint(r.Kind)
+		} else {
+			r.Endpoint = w.Endpoint(r.Kind, r.Time())
+		}
+	default:
+		log.WithField("kind", r.Kind).Warning("skipping request of unknown kind")
+		return false
+	}
+	return true
+}
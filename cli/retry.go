@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/theMomax/openefs-csv-feeder/config"
+	"github.com/theMomax/openefs-csv-feeder/mocktime"
+	"github.com/theMomax/openefs-csv-feeder/writer"
+)
+
+var retryFailedCtx = &cobra.Command{
+	Use:   "retry-failed [file]",
+	Short: "Re-sends requests quarantined using " + writer.PathOnError + "=" + writer.OnErrorQuarantine + ".",
+	Long:  `Re-sends requests quarantined using ` + writer.PathOnError + `=` + writer.OnErrorQuarantine + ` to openefs. The file defaults to ` + writer.PathDeadLetter + `. Requests, that fail again, are quarantined once more, i.e. afterwards the dead-letter file only holds the requests that still fail. Requests are neither batched nor recorded, and a dry-run leaves the dead-letter file unchanged.`,
+	Args:  cobra.MaximumNArgs(1),
+	Run:   retryFailed,
+}
+
+func init() {
+	config.RootCtx.AddCommand(retryFailedCtx)
+}
+
+func retryFailed(cmd *cobra.Command, args []string) {
+	deadLetter := config.Viper.GetString(writer.PathDeadLetter)
+	path := deadLetter
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	entries := make([]*writer.Entry, 0)
+	err := writer.ReadEntries(path, func(e *writer.Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// requests failing again are collected in a temporary file, that replaces
+	// the retried file once all requests were sent, so a crash loses nothing.
+	// A dry-run sends nothing, so the retried file is left as it is.
+	var tmp string
+	if same(path, deadLetter) && config.Viper.GetString(writer.PathDryRun) == "" {
+		tmp = path + ".tmp"
+		if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+			log.Fatal(err)
+		}
+		config.Viper.Set(writer.PathDeadLetter, tmp)
+	}
+	config.Viper.Set(writer.PathOnError, writer.OnErrorQuarantine)
+	// the quarantined requests are sent as they are
+	config.Viper.Set(writer.PathBatchSize, 1)
+	config.Viper.Set(writer.PathRecord, "")
+
+	s, err := writer.NewSinkFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := writer.NewWriterFromConfig(s)
+	u := mocktime.NewUpdaterFromConfig(s)
+
+	count := 0
+	for _, e := range entries {
+		if !rewrite(e.Request, w, u) {
+			continue
+		}
+		if err := s.Send(e.Request); err != nil {
+			fatal(err)
+		}
+		count++
+	}
+	if err := s.Close(); err != nil {
+		fatal(err)
+	}
+	if tmp != "" {
+		if err := os.Rename(tmp, path); err != nil {
+			log.Fatal(err)
+		}
+	}
+	log.WithField("amount", count).Info("completed")
+}
+
+// same returns true if both paths refer to the same file.
+func same(a, b string) bool {
+	a, aerr := filepath.Abs(a)
+	b, berr := filepath.Abs(b)
+	return aerr == nil && berr == nil && a == b
+}
@@ -1,12 +1,7 @@
 package cli
 
 import (
-	"bufio"
-	"fmt"
-	"math"
-	"os"
-	"strconv"
-	"strings"
+	"errors"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -16,23 +11,27 @@ import (
 	"github.com/theMomax/openefs-csv-feeder/config"
 	"github.com/theMomax/openefs-csv-feeder/reader"
 	"github.com/theMomax/openefs-csv-feeder/writer"
-	"github.com/theMomax/openefs/models/production"
-	"github.com/theMomax/openefs/models/production/weather"
 )
 
 // Config paths
 const (
 	PathBatchSize = "cli.batchsize"
 	PathStartTime = "cli.starttime"
+	PathEndTime   = "cli.endtime"
+	PathDuration  = "cli.duration"
 )
 
 func init() {
 	config.RootCtx.Run = run
 
-	config.RootCtx.PersistentFlags().UintP(PathBatchSize, "b", 24, "the number of hours to be processed per batch (execution will pause before each batch)")
+	config.RootCtx.PersistentFlags().UintP(PathBatchSize, "b", 24, "the number of time-steps to be processed per batch (execution will pause before each batch)")
 	config.Viper.BindPFlag(PathBatchSize, config.RootCtx.PersistentFlags().Lookup(PathBatchSize))
-	config.RootCtx.PersistentFlags().Int64P(PathStartTime, "s", math.MinInt64, "the unix time (in seconds) where the reader starts (if older than the oldest input-value, the latter is used)")
+	config.RootCtx.PersistentFlags().StringP(PathStartTime, "s", "", "the time (unix seconds or RFC3339) where the reader starts (if older than the oldest input-value, the latter is used)")
 	config.Viper.BindPFlag(PathStartTime, config.RootCtx.PersistentFlags().Lookup(PathStartTime))
+	config.RootCtx.PersistentFlags().String("end", "", "the time (unix seconds or RFC3339) before which the reader stops (defaults to the latest input-value)")
+	config.Viper.BindPFlag(PathEndTime, config.RootCtx.PersistentFlags().Lookup("end"))
+	config.RootCtx.PersistentFlags().Duration("duration", 0, "the length of the window to be processed, counted from the first time-step (alternative to --end)")
+	config.Viper.BindPFlag(PathDuration, config.RootCtx.PersistentFlags().Lookup("duration"))
 	config.OnInitialize(func() {
 		log = config.NewLogger()
 	})
@@ -46,61 +45,158 @@ func Execute() error {
 }
 
 func run(cmd *cobra.Command, args []string) {
-	w := writer.NewWriterFromConfig()
+	s, err := writer.NewSinkFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+	w := writer.NewWriterFromConfig(s)
+	u := mocktime.NewUpdaterFromConfig(s)
+
 	r, err := reader.NewReaderFromConfig()
 	if err != nil {
 		log.Fatal(err)
 	}
+	defer r.Close()
+
+	p := &progress{}
+	base := newControllerFromConfig(u)
+	ctl := base
+	if address := config.Viper.GetString(PathControlAddress); address != "" {
+		if ctl, err = newRemoteController(ctl, p, address, config.Viper.GetBool(PathControlPaused)); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	start, end, err := window(r)
+	if err != nil {
+		log.Fatal(err)
+	}
 
-	batchSize := config.Viper.GetUint(PathBatchSize)
-	skip := uint(0)
-	count := uint(0)
+	var cp *checkpoint
+	cpPath := config.Viper.GetString(PathCheckpoint)
+	if cpPath != "" {
+		if cp, err = newCheckpoint(r.Files()); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if config.Viper.GetBool(PathResume) {
+		if cp == nil {
+			log.Fatal("--resume requires " + PathCheckpoint)
+		}
+		previous, err := loadCheckpoint(cpPath)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := previous.check(cp); err != nil {
+			log.WithField("path", cpPath).Fatal("refusing to resume: ", err)
+		}
+		start = time.Unix(previous.Timestamp, 0).Add(config.Viper.GetDuration(reader.PathStepSize))
+		log.WithField("date", start).Info("resuming")
+	}
+	if cp != nil && config.Viper.GetString(writer.PathDryRun) != "" {
+		// nothing reaches openefs, so a later real run must not skip anything
+		log.WithField("path", cpPath).Info("not saving checkpoints during dry-run")
+		cp = nil
+	}
 
-	r.ForEach(
-		func(date time.Time, production *production.Data) {
+	it := r.NewIterator(start, end)
+	for it.HasNext() {
+		if seek := ctl.wait(it.Current()); seek != nil {
+			log.WithField("date", *seek).Info("seeking")
+			if err := it.Seek(*seek); err != nil {
+				log.WithError(err).Error("seek failed")
+			}
+			continue
+		}
+
+		date, production, weather := it.Next(true, true)
+		p.step(date)
+
+		if _, ok := base.(*clockController); ok {
+			log.WithField("date", date).Info("reached time-step")
+		} else {
 			log.WithField("date", date).Info("updated mocktime")
-			err := mocktime.Update(date)
+			err := u.Update(date)
 			if err != nil {
-				log.Fatal(err)
+				fatal(err)
 			}
+		}
 
-			if count%batchSize == 0 {
-				if skip == 0 {
-					skip = pause(date)
-				} else {
-					skip--
-				}
-				count = 1
-			} else {
-				count++
-			}
+		err := w.WriteProduction(date, production)
+		if err != nil {
+			fatal(err)
+		}
 
-			err = w.WriteProduction(date, production)
-			if err != nil {
-				log.Fatal(err)
+		dates := r.ForecastTimes(date)
+		err = w.WriteWeatherForecast(dates, weather)
+		if err != nil {
+			fatal(err)
+		}
+		p.wrote(1, uint(len(dates)))
+
+		if cp != nil {
+			if err := writer.Flush(s); err != nil {
+				fatal(err)
 			}
-		},
-		func(dates []time.Time, weather []*weather.Data) {
-			for i := range dates {
-				err := w.WriteWeather(dates[i], weather[i])
-				if err != nil {
-					log.Fatal(err)
-				}
+			if err := cp.save(cpPath, date); err != nil {
+				log.Fatal(err)
 			}
-		},
-		time.Unix(config.Viper.GetInt64(PathStartTime), 0),
-	)
+		}
+	}
+	if c, ok := base.(*clockController); ok {
+		c.reset()
+	}
+	if err := r.Err(); err != nil {
+		log.Fatal(err)
+	}
+	for series, counts := range r.Filled() {
+		l := log.WithField("series", series)
+		for strategy, n := range counts {
+			l = l.WithField(strategy, n)
+		}
+		l.Info("filled missing values")
+	}
+	for series, n := range r.Gaps() {
+		log.WithField("series", series).WithField("amount", n).Warning("values remained missing")
+	}
+	if err := s.Close(); err != nil {
+		fatal(err)
+	}
 	log.Info("completed")
 }
 
-func pause(date time.Time) uint {
-	r := bufio.NewReader(os.Stdin)
-	fmt.Printf("(%s | %d) > ", date.String(), date.Unix())
-	text, _ := r.ReadString('\n')
-	text = strings.TrimSpace(text)
-	nr, err := strconv.ParseUint(text, 10, 64)
-	if err != nil || nr == 0 {
-		return 0
+// window returns the bounds configured for the iteration. The zero time
+// stands for no bound.
+func window(r *reader.Reader) (start, end time.Time, err error) {
+	if v := config.Viper.GetString(PathStartTime); v != "" {
+		if start, err = parseTime(v); err != nil {
+			return start, end, err
+		}
+	}
+
+	v := config.Viper.GetString(PathEndTime)
+	d := config.Viper.GetDuration(PathDuration)
+	if v != "" && d != 0 {
+		return start, end, errors.New("--end and --duration are mutually exclusive")
+	} else if v != "" {
+		end, err = parseTime(v)
+	} else if d > 0 {
+		end = r.NewIterator(start).Current().Add(d)
+	} else if d < 0 {
+		err = errors.New("--duration must not be negative")
+	}
+	if err == nil && !end.IsZero() && !end.After(start) {
+		err = errors.New("the end must be after the start")
+	}
+	return start, end, err
+}
+
+// fatal exits after logging err, including the details of a
+// writer.WriteError.
+func fatal(err error) {
+	var we *writer.WriteError
+	if errors.As(err, &we) {
+		log.WithFields(logrus.Fields(we.Fields())).Fatal(err)
 	}
-	return uint(nr) - 1
+	log.Fatal(err)
 }
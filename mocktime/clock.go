@@ -0,0 +1,119 @@
+package mocktime
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathSpeed        = "mocktime.speed"
+	PathTickInterval = "mocktime.tickinterval"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().Float64(PathSpeed, 0, "if positive, the mock-time advances continuously at the given multiple of the wall-clock's speed instead of jumping from one time-step to the next")
+	config.Viper.BindPFlag(PathSpeed, config.RootCtx.PersistentFlags().Lookup(PathSpeed))
+
+	config.RootCtx.PersistentFlags().Duration(PathTickInterval, time.Second, "the wall-clock-interval at which the continuously advancing mock-time is pushed to openefs")
+	config.Viper.BindPFlag(PathTickInterval, config.RootCtx.PersistentFlags().Lookup(PathTickInterval))
+}
+
+// Clock is a simulated clock, that advances speed times as fast as the
+// wall-clock once started. On every tick the simulated time is pushed to
+// openefs using the Updater.
+type Clock struct {
+	updater  *Updater
+	speed    float64
+	interval time.Duration
+	real     clockwork.Clock
+	fake     clockwork.FakeClock
+	stop     chan struct{}
+	done     chan struct{}
+	m        sync.Mutex
+	err      error
+}
+
+func NewClock(updater *Updater, start time.Time, speed float64, interval time.Duration) *Clock {
+	return &Clock{
+		updater:  updater,
+		speed:    speed,
+		interval: interval,
+		real:     clockwork.NewRealClock(),
+		fake:     clockwork.NewFakeClockAt(start),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func NewClockFromConfig(updater *Updater, start time.Time) *Clock {
+	interval := config.Viper.GetDuration(PathTickInterval)
+	if interval <= 0 {
+		config.InvalidConfiguration(PathTickInterval, "a positive duration")
+	}
+	return NewClock(updater, start, config.Viper.GetFloat64(PathSpeed), interval)
+}
+
+// Start pushes the initial time and starts advancing the Clock.
+func (c *Clock) Start() error {
+	if err := c.updater.Update(c.fake.Now()); err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(c.done)
+		startReal := c.real.Now()
+		startFake := c.fake.Now()
+		for {
+			select {
+			case <-c.stop:
+				return
+			case <-c.real.After(c.interval):
+			}
+			target := startFake.Add(time.Duration(float64(c.real.Now().Sub(startReal)) * c.speed))
+			c.fake.Advance(target.Sub(c.fake.Now()))
+			log.WithField("date", c.fake.Now()).Debug("updated mocktime")
+			if err := c.updater.Update(c.fake.Now()); err != nil {
+				c.m.Lock()
+				c.err = err
+				c.m.Unlock()
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// Stop stops advancing the Clock.
+func (c *Clock) Stop() {
+	close(c.stop)
+	<-c.done
+}
+
+// Now returns the simulated time.
+func (c *Clock) Now() time.Time {
+	return c.fake.Now()
+}
+
+// WaitUntil blocks until the simulated time has reached t. It returns early
+// if the Clock stopped due to a failed update.
+func (c *Clock) WaitUntil(t time.Time) error {
+	d := t.Sub(c.fake.Now())
+	if d > 0 {
+		select {
+		case <-c.fake.After(d):
+		case <-c.done:
+		}
+	}
+	return c.Err()
+}
+
+// Err returns the error, that stopped the Clock.
+func (c *Clock) Err() error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return c.err
+}
@@ -1,14 +1,14 @@
 package mocktime
 
 import (
-	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/jonboulle/clockwork"
+	"github.com/sirupsen/logrus"
 	"github.com/theMomax/openefs-csv-feeder/config"
+	"github.com/theMomax/openefs-csv-feeder/writer"
 )
 
 // Config paths
@@ -16,19 +16,43 @@ const (
 	PathMockTimeAddress = "mocktime.address"
 )
 
-var fakeClock clockwork.FakeClock
-
 func init() {
 	config.RootCtx.PersistentFlags().StringP(PathMockTimeAddress, "m", "http://localhost:8090", "address for mock-time endpoint")
 	config.Viper.BindPFlag(PathMockTimeAddress, config.RootCtx.PersistentFlags().Lookup(PathMockTimeAddress))
+	config.OnInitialize(func() {
+		log = config.NewLogger()
+	})
 }
 
-func Update(t time.Time) error {
-	resp, err := http.Get(config.Viper.GetString(PathMockTimeAddress) + strings.ReplaceAll("/utils/time/mocktime/:unixtimestamp", ":unixtimestamp", strconv.FormatInt(t.Unix(), 10)))
-	if err != nil {
-		return errors.New("mock-time update failed: " + err.Error())
-	} else if resp.StatusCode != http.StatusOK {
-		return errors.New("mock-time update failed: " + strconv.Itoa(resp.StatusCode) + "; " + resp.Status)
+var log *logrus.Logger
+
+// Updater sets openefs' mock-time.
+type Updater struct {
+	address string
+	sink    writer.Sink
+}
+
+func NewUpdater(address string, sink writer.Sink) *Updater {
+	return &Updater{
+		address: address + "/utils/time/mocktime/:unixtimestamp",
+		sink:    sink,
 	}
-	return nil
+}
+
+func NewUpdaterFromConfig(sink writer.Sink) *Updater {
+	return NewUpdater(config.Viper.GetString(PathMockTimeAddress), sink)
+}
+
+func (u *Updater) Update(t time.Time) error {
+	return u.sink.Send(&writer.Request{
+		Kind:      writer.KindMockTime,
+		Timestamp: t.Unix(),
+		Method:    http.MethodGet,
+		Endpoint:  u.Endpoint(t),
+	})
+}
+
+// Endpoint returns the URL used for setting the mock-time to t.
+func (u *Updater) Endpoint(t time.Time) string {
+	return strings.ReplaceAll(u.address, ":unixtimestamp", strconv.FormatInt(t.Unix(), 10))
 }
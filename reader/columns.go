@@ -0,0 +1,217 @@
+package reader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/theMomax/openefs/models/production"
+	"github.com/theMomax/openefs/models/production/weather"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathColumns = "reader.columns"
+)
+
+// timeField is the name of the field holding the time in all input-files.
+const timeField = "Time"
+
+// Column maps a csv-header to a model-field, i.e. the csv-tag of a field of
+// production.Data or weather.Data (or 'Time'). The column's values are
+// converted by value*Factor + Offset. If Unit is set, the result is converted
+// from Unit to the field's unit afterwards.
+type Column struct {
+	Header string
+	Field  string
+	Factor float64
+	Offset float64
+	Unit   string
+}
+
+// columnConfig is a single entry of the list configured under PathColumns.
+// Factor defaults to 1.
+type columnConfig struct {
+	Header string   `mapstructure:"header"`
+	Field  string   `mapstructure:"field"`
+	Factor *float64 `mapstructure:"factor"`
+	Offset float64  `mapstructure:"offset"`
+	Unit   string   `mapstructure:"unit"`
+}
+
+// NewColumnsFromConfig reads the column-mapping from the list configured under
+// PathColumns, e.g.:
+//
+//	reader:
+//	  columns:
+//	    - header: temp_2m_C
+//	      field: temperature
+//	    - header: pv_kw
+//	      field: production
+//	      unit: kW
+//
+// Header and field may be equal, e.g. to only declare a column's unit.
+func NewColumnsFromConfig() ([]Column, error) {
+	configs := make([]columnConfig, 0)
+	if err := config.Viper.UnmarshalKey(PathColumns, &configs); err != nil {
+		return nil, errors.New("illegal " + PathColumns + ": " + err.Error())
+	}
+
+	columns := make([]Column, len(configs))
+	for i, c := range configs {
+		columns[i] = Column{
+			Header: c.Header,
+			Field:  c.Field,
+			Factor: 1,
+			Offset: c.Offset,
+			Unit:   c.Unit,
+		}
+		if c.Factor != nil {
+			columns[i].Factor = *c.Factor
+		}
+	}
+	return columns, nil
+}
+
+// validateColumns checks, that all columns refer to existing model-fields and
+// no header is mapped twice.
+func validateColumns(columns []Column) error {
+	headers := make(map[string]bool)
+	for _, c := range columns {
+		if c.Header == "" || c.Field == "" {
+			return errors.New("column-mapping requires header and field")
+		}
+		if headers[c.Header] {
+			return errors.New("header '" + c.Header + "' is mapped twice")
+		}
+		headers[c.Header] = true
+
+		if c.Field == timeField {
+			if c.Factor != 1 || c.Offset != 0 {
+				return errors.New("column '" + c.Header + "' is mapped to " + timeField + ", which cannot be scaled")
+			}
+		} else if fieldIndex(reflect.TypeOf(production.Data{}), c.Field) == nil && fieldIndex(reflect.TypeOf(weather.Data{}), c.Field) == nil {
+			return errors.New("column '" + c.Header + "' is mapped to unknown field '" + c.Field + "'")
+		}
+	}
+	return nil
+}
+
+// fieldScale converts the value of a single field of a decoded row.
+type fieldScale struct {
+	index  []int
+	factor float64
+	offset float64
+}
+
+// scaling holds the conversions applicable to the rows of a single file.
+type scaling []fieldScale
+
+// apply converts the fields of row, which must be a value of the type
+// passed to openCSV.
+func (s scaling) apply(row interface{}) {
+	if len(s) == 0 {
+		return
+	}
+	v := reflect.ValueOf(row)
+	for _, f := range s {
+		fv := v.FieldByIndex(f.index)
+		fv.SetFloat(fv.Float()*f.factor + f.offset)
+	}
+}
+
+// mapHeader renames the header-row read from r according to the format's
+// columns. It returns a reader yielding the renamed header followed by the
+// remaining input, as well as the scaling for the columns present in the file.
+func (f *CSVFormat) mapHeader(path string, r io.Reader, out interface{}) (io.Reader, scaling, error) {
+	if f == nil || len(f.Columns) == 0 {
+		return r, nil, nil
+	}
+
+	br := bufio.NewReader(r)
+	line, err := br.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, nil, err
+	}
+	headers, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present := make(map[string]bool)
+	for _, h := range headers {
+		present[strings.TrimSpace(h)] = true
+	}
+
+	var s scaling
+	for i, h := range headers {
+		c, ok := f.column(strings.TrimSpace(h))
+		if !ok {
+			continue
+		}
+		if c.Header != c.Field && present[c.Field] {
+			return nil, nil, errors.New("input-file '" + path + "' holds column '" + c.Field + "' as well as '" + c.Header + "', which is mapped to it")
+		}
+		headers[i] = c.Field
+
+		if c.Factor == 1 && c.Offset == 0 {
+			continue
+		}
+		index := fieldIndex(reflect.TypeOf(out), c.Field)
+		if index == nil {
+			log.WithField("filepath", path).WithField("header", c.Header).WithField("field", c.Field).Warning("mapped column is not used by this input-file")
+			continue
+		}
+		s = append(s, fieldScale{
+			index:  index,
+			factor: c.Factor,
+			offset: c.Offset,
+		})
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(headers)
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, nil, err
+	}
+	return io.MultiReader(&buf, br), s, nil
+}
+
+func (f *CSVFormat) column(header string) (Column, bool) {
+	for _, c := range f.Columns {
+		if c.Header == header {
+			return c, true
+		}
+	}
+	return Column{}, false
+}
+
+// fieldIndex returns the index-sequence of the float-field tagged with the
+// given csv-tag within t (including embedded structs), or nil.
+func fieldIndex(t reflect.Type, tag string) []int {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			if index := fieldIndex(f.Type, tag); index != nil {
+				return append([]int{i}, index...)
+			}
+		} else if f.Tag.Get("csv") == tag && f.Type.Kind() == reflect.Float64 {
+			return []int{i}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package reader
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gocarina/gocsv"
+	"github.com/theMomax/openefs/models/production"
+	"github.com/theMomax/openefs/models/production/weather"
+	timeutils "github.com/theMomax/openefs/utils/time"
+)
+
+type weatherCSVData struct {
+	*weather.Data
+	Time string `csv:"Time"`
+}
+
+type prodCSVData struct {
+	*production.Data
+	Time string `csv:"Time"`
+}
+
+// DefaultWeatherPattern matches weather-input-files named
+// 'forecast_*(s|m|h|d)_ahead.csv', where * is a non-negative integer.
+const DefaultWeatherPattern = `^forecast_(?P<horizon>\d+[smhd])_ahead\.csv$`
+
+// CSVWeatherSource is a WeatherSource reading from a folder containing one
+// file per forecast-distance. The distance is taken from the capture-group
+// named 'horizon' of the file-name-pattern.
+type CSVWeatherSource struct {
+	timestep time.Duration
+	format   *CSVFormat
+	files    map[time.Duration]csvWeatherFile
+}
+
+type csvWeatherFile struct {
+	path    string
+	isDaily bool
+}
+
+// NewCSVWeatherSource searches the given folder for weather-input-files
+// matching pattern. Values from daily files are not overwritten by later rows,
+// that fall into the same time-step. The files' values are interpreted as
+// described by format.
+func NewCSVWeatherSource(weatherBasePath string, pattern *regexp.Regexp, timestep time.Duration, format *CSVFormat) (*CSVWeatherSource, error) {
+	group := -1
+	for i, name := range pattern.SubexpNames() {
+		if name == "horizon" {
+			group = i
+		}
+	}
+	if group < 0 {
+		return nil, errors.New("weather-file-pattern '" + pattern.String() + "' has no capture-group named 'horizon'")
+	}
+
+	files := make(map[time.Duration]csvWeatherFile, 0)
+
+	err := filepath.Walk(weatherBasePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		log.WithField("filepath", path).Trace("found candidate-file")
+		if info.IsDir() {
+			return nil
+		}
+		match := pattern.FindStringSubmatch(info.Name())
+		if match == nil {
+			if strings.HasSuffix(info.Name(), ".csv") {
+				log.WithField("filepath", path).WithField("pattern", pattern.String()).Warning("possible input-file does not match pattern")
+			}
+			return nil
+		}
+		distance, unit, err := parseHorizon(match[group])
+		if err != nil {
+			log.WithField("filepath", path).WithField("pattern", pattern.String()).WithError(err).Warning("possible input-file has illegal horizon, expected a non-negative integer followed by one of (s|m|h|d)")
+			return nil
+		}
+
+		files[distance] = csvWeatherFile{
+			path:    path,
+			isDaily: unit == 24*time.Hour,
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CSVWeatherSource{
+		timestep: timestep,
+		format:   format,
+		files:    files,
+	}, nil
+}
+
+// Horizons implements WeatherSource.
+func (s *CSVWeatherSource) Horizons() ([]time.Duration, error) {
+	horizons := make([]time.Duration, 0, len(s.files))
+	for d := range s.files {
+		horizons = append(horizons, d)
+	}
+	return horizons, nil
+}
+
+// Files implements FileSource.
+func (s *CSVWeatherSource) Files() []string {
+	files := make([]string, 0, len(s.files))
+	for _, f := range s.files {
+		files = append(files, f.path)
+	}
+	sort.Strings(files)
+	return files
+}
+
+// OpenWeather implements WeatherSource.
+func (s *CSVWeatherSource) OpenWeather(distance time.Duration) (WeatherStream, error) {
+	fi, ok := s.files[distance]
+	if !ok {
+		return &csvWeatherStream{}, nil
+	}
+
+	log.WithField("filepath", fi.path).WithField("isDaily", fi.isDaily).WithField("duration_ahead", distance).Debug("opening next weather-input-file")
+	f, um, sc, err := openCSV(fi.path, weatherCSVData{}, s.format)
+	if err != nil {
+		return nil, err
+	}
+
+	stream := &csvWeatherStream{
+		path:    fi.path,
+		file:    f,
+		um:      um,
+		time:    s.format.timeSequence(),
+		scaling: sc,
+	}
+	if fi.isDaily {
+		stream.seen = make(map[time.Time]bool)
+		stream.timestep = s.timestep
+	}
+	return stream, nil
+}
+
+type csvWeatherStream struct {
+	path     string
+	file     *os.File
+	um       *gocsv.Unmarshaller
+	time     *TimeSequence
+	scaling  scaling
+	timestep time.Duration
+	// Rows of daily files are deduplicated, keeping the first row per
+	// rounded time. Unless the input is known to be ordered, all rounded
+	// times are remembered in seen, otherwise only the previous one in last.
+	seen map[time.Time]bool
+	last *time.Time
+}
+
+// assumeOrdered implements orderedStream.
+func (s *csvWeatherStream) assumeOrdered() {
+	s.seen = nil
+}
+
+func (s *csvWeatherStream) Next() (time.Time, *weather.Data, error) {
+	if s.um == nil {
+		return time.Time{}, nil, io.EOF
+	}
+	for {
+		v, err := s.um.Read()
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		w := v.(weatherCSVData)
+		log.WithField("element", w).Trace()
+		t, err := parseTime(s.time, s.path, w.Time)
+		if err == errSkipRow {
+			continue
+		} else if err != nil {
+			return time.Time{}, nil, err
+		}
+		if s.seen != nil {
+			r := timeutils.Round(t, s.timestep)
+			if s.seen[r] {
+				continue
+			}
+			s.seen[r] = true
+		} else if s.timestep != 0 {
+			// the input is ordered by time, so duplicates are adjacent
+			r := timeutils.Round(t, s.timestep)
+			if s.last != nil && s.last.Equal(r) {
+				continue
+			}
+			s.last = &r
+		}
+		s.scaling.apply(w)
+		return t, w.Data, nil
+	}
+}
+
+func (s *csvWeatherStream) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+// CSVProductionSource is a ProductionSource reading from a single csv-file.
+type CSVProductionSource struct {
+	path   string
+	format *CSVFormat
+}
+
+// NewCSVProductionSource returns a ProductionSource for the given file, whose
+// values are interpreted as described by format.
+func NewCSVProductionSource(productionAddress string, format *CSVFormat) *CSVProductionSource {
+	return &CSVProductionSource{
+		path:   productionAddress,
+		format: format,
+	}
+}
+
+// Files implements FileSource.
+func (s *CSVProductionSource) Files() []string {
+	return []string{s.path}
+}
+
+// OpenProduction implements ProductionSource.
+func (s *CSVProductionSource) OpenProduction() (ProductionStream, error) {
+	f, um, sc, err := openCSV(s.path, prodCSVData{}, s.format)
+	if err != nil {
+		return nil, err
+	}
+
+	return &csvProductionStream{
+		source:  s,
+		file:    f,
+		um:      um,
+		time:    s.format.timeSequence(),
+		scaling: sc,
+	}, nil
+}
+
+type csvProductionStream struct {
+	source  *CSVProductionSource
+	file    *os.File
+	um      *gocsv.Unmarshaller
+	time    *TimeSequence
+	scaling scaling
+}
+
+func (s *csvProductionStream) Next() (time.Time, *production.Data, error) {
+	for {
+		v, err := s.um.Read()
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		p := v.(prodCSVData)
+		t, err := parseTime(s.time, s.source.path, p.Time)
+		if err == errSkipRow {
+			continue
+		} else if err != nil {
+			return time.Time{}, nil, err
+		}
+		s.scaling.apply(p)
+		return t, p.Data, nil
+	}
+}
+
+func (s *csvProductionStream) Close() error {
+	return s.file.Close()
+}
+
+// parseHorizon parses descriptions like '15m', '6h' or '2d' into the described
+// forecast-distance and the unit used.
+func parseHorizon(description string) (distance, unit time.Duration, err error) {
+	if description == "" {
+		return 0, 0, errors.New("empty duration-description")
+	}
+	switch description[len(description)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	default:
+		return 0, 0, errors.New("illegal duration-unit '" + string(description[len(description)-1]) + "'")
+	}
+
+	number, err := strconv.ParseUint(description[:len(description)-1], 10, 32)
+	if err != nil {
+		return 0, 0, errors.New("illegal duration-number: " + err.Error())
+	}
+	return time.Duration(number) * unit, unit, nil
+}
+
+// parseTime parses the next value of a time-column read from the file at
+// path. Skipped rows are logged.
+func parseTime(seq *TimeSequence, path string, value string) (time.Time, error) {
+	t, err := seq.Parse(value)
+	if err == errSkipRow {
+		log.WithField("filepath", path).WithField("time", value).Warning("skipping row with ambiguous or missing local time")
+	} else if err != nil {
+		return t, errors.New("illegal time '" + value + "' in '" + path + "': " + err.Error())
+	}
+	return t, err
+}
+
+// openCSV opens the given file and prepares an Unmarshaller, that reads one
+// row at a time into values of the same type as out. The headers are mapped
+// as described by format. The returned scaling has to be applied to every row.
+func openCSV(path string, out interface{}, format *CSVFormat) (*os.File, *gocsv.Unmarshaller, scaling, error) {
+	f, err := os.OpenFile(path, os.O_RDONLY, os.ModePerm)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	r, s, err := format.mapHeader(path, f, out)
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+
+	um, err := gocsv.NewUnmarshaller(csv.NewReader(r), out)
+	if err != nil {
+		f.Close()
+		return nil, nil, nil, err
+	}
+	return f, um, s, nil
+}
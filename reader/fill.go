@@ -0,0 +1,180 @@
+package reader
+
+import (
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathFillProduction = "reader.fill.production"
+	PathFillWeather    = "reader.fill.weather"
+	PathFillMaxAge     = "reader.fill.maxage"
+	PathFillSeason     = "reader.fill.season"
+)
+
+// Strategies for filling missing values
+const (
+	FillNone     = "none"
+	FillLOCF     = "locf"
+	FillLinear   = "linear"
+	FillSeasonal = "seasonal"
+	FillZero     = "zero"
+)
+
+// Series, that are filled independently
+const (
+	seriesProduction = "production"
+	seriesWeather    = "weather"
+)
+
+func init() {
+	strategies := FillNone + ", " + FillLOCF + " (last value carried forward), " + FillLinear + " (interpolation between neighbours, only using values already read when streaming), " + FillSeasonal + " (value one " + PathFillSeason + " earlier) or " + FillZero
+
+	config.RootCtx.PersistentFlags().String(PathFillProduction, FillLOCF, "how missing production-values are filled: "+strategies)
+	config.Viper.BindPFlag(PathFillProduction, config.RootCtx.PersistentFlags().Lookup(PathFillProduction))
+
+	config.RootCtx.PersistentFlags().String(PathFillWeather, FillLOCF, "how missing weather-values are filled (one of the strategies listed for "+PathFillProduction+")")
+	config.Viper.BindPFlag(PathFillWeather, config.RootCtx.PersistentFlags().Lookup(PathFillWeather))
+
+	config.RootCtx.PersistentFlags().Duration(PathFillMaxAge, 0, "the maximum age of replacements, i.e. the distance to the values used by "+FillLOCF+" and "+FillLinear+"; values missing beyond are counted as gaps (unlimited if 0)")
+	config.Viper.BindPFlag(PathFillMaxAge, config.RootCtx.PersistentFlags().Lookup(PathFillMaxAge))
+
+	config.RootCtx.PersistentFlags().Duration(PathFillSeason, 24*time.Hour, "the period used by "+FillSeasonal)
+	config.Viper.BindPFlag(PathFillSeason, config.RootCtx.PersistentFlags().Lookup(PathFillSeason))
+}
+
+// Fill describes how missing values of a series are replaced.
+type Fill struct {
+	Strategy string
+	MaxAge   time.Duration
+	Season   time.Duration
+}
+
+// NewFillFromConfig returns the Fills for production and weather as
+// configured by this package's config paths.
+func NewFillFromConfig() (production, weather *Fill, err error) {
+	if production, err = newFill(config.Viper.GetString(PathFillProduction)); err != nil {
+		return nil, nil, err
+	}
+	if weather, err = newFill(config.Viper.GetString(PathFillWeather)); err != nil {
+		return nil, nil, err
+	}
+	return production, weather, nil
+}
+
+func newFill(strategy string) (*Fill, error) {
+	switch strategy {
+	case FillNone, FillLOCF, FillLinear, FillSeasonal, FillZero:
+	default:
+		return nil, errors.New("unknown fill-strategy '" + strategy + "'")
+	}
+	season := config.Viper.GetDuration(PathFillSeason)
+	if strategy == FillSeasonal && season <= 0 {
+		return nil, errors.New(PathFillSeason + " must be positive")
+	}
+	return &Fill{
+		Strategy: strategy,
+		MaxAge:   config.Viper.GetDuration(PathFillMaxAge),
+		Season:   season,
+	}, nil
+}
+
+// series gives access to the values of a single series. The values must be
+// pointers to structs holding float-fields only.
+type series struct {
+	name   string
+	lookup func(t time.Time) interface{}
+	oldest *time.Time
+	latest *time.Time
+	zero   func() interface{}
+}
+
+// fill returns a replacement for the value missing at date, or nil.
+func (r *Reader) fill(f *Fill, s series, date time.Time) interface{} {
+	date = r.round(date)
+	var v interface{}
+	switch f.Strategy {
+	case FillLOCF:
+		v, _ = r.neighbour(f, s, date, -1)
+	case FillLinear:
+		v = r.interpolate(f, s, date)
+	case FillSeasonal:
+		if t := date.Add(-f.Season); !r.isEvicted(t) {
+			v = s.lookup(t)
+		}
+	case FillZero:
+		v = s.zero()
+	}
+
+	if v != nil {
+		if r.filled[s.name] == nil {
+			r.filled[s.name] = make(map[string]uint)
+		}
+		r.filled[s.name][f.Strategy]++
+	}
+	return v
+}
+
+// neighbour searches the closest value before (direction -1) or after
+// (direction 1) date, limited by the Fill's MaxAge.
+func (r *Reader) neighbour(f *Fill, s series, date time.Time, direction int) (interface{}, time.Time) {
+	if s.oldest == nil || s.latest == nil {
+		return nil, time.Time{}
+	}
+	step := time.Duration(direction) * r.productionTimestep
+	for t := date.Add(step); !r.isEvicted(t); t = t.Add(step) {
+		if (direction < 0 && t.Before(*s.oldest)) || (direction > 0 && t.After(*s.latest)) {
+			break
+		}
+		if f.MaxAge > 0 && (t.Sub(date) > f.MaxAge || date.Sub(t) > f.MaxAge) {
+			break
+		}
+		if v := s.lookup(t); v != nil {
+			return v, t
+		}
+	}
+	return nil, time.Time{}
+}
+
+// interpolate linearly interpolates all fields between the neighbours of date.
+func (r *Reader) interpolate(f *Fill, s series, date time.Time) interface{} {
+	before, tb := r.neighbour(f, s, date, -1)
+	if before == nil {
+		return nil
+	}
+	after, ta := r.neighbour(f, s, date, 1)
+	if after == nil {
+		return nil
+	}
+
+	frac := float64(date.Sub(tb)) / float64(ta.Sub(tb))
+	b, a := reflect.ValueOf(before).Elem(), reflect.ValueOf(after).Elem()
+	v := reflect.New(b.Type())
+	for i := 0; i < b.NumField(); i++ {
+		if b.Field(i).Kind() == reflect.Float64 {
+			v.Elem().Field(i).SetFloat(b.Field(i).Float() + (a.Field(i).Float()-b.Field(i).Float())*frac)
+		}
+	}
+	return v.Interface()
+}
+
+// gap counts a value, that could not be replaced.
+func (r *Reader) gap(series string, date time.Time) {
+	log.WithField("series", series).WithField("date", date).Debug("value missing")
+	r.gaps[series]++
+}
+
+// Filled returns how many values were filled per series and strategy.
+func (r *Reader) Filled() map[string]map[string]uint {
+	return r.filled
+}
+
+// Gaps returns how many values per series were missing although replacement
+// was requested.
+func (r *Reader) Gaps() map[string]uint {
+	return r.gaps
+}
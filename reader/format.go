@@ -0,0 +1,78 @@
+package reader
+
+import (
+	"errors"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// CSVFormat describes how the values of csv-input-files are interpreted. A
+// nil CSVFormat expects RFC3339 timestamps and headers matching the models'
+// csv-tags. The Columns' factors and offsets are applied as they are, i.e.
+// units must already be resolved (see NewCSVFormat).
+type CSVFormat struct {
+	Time    *TimeParser
+	Columns []Column
+}
+
+// NewCSVFormat validates the given columns and resolves their units. Values
+// are converted to the unit targets holds for the column's field. Values
+// accumulated over a time-step (like energy) are converted to their average
+// rate (like power) over step.
+func NewCSVFormat(timeParser *TimeParser, columns []Column, targets map[string]string, step time.Duration) (*CSVFormat, error) {
+	if err := validateColumns(columns); err != nil {
+		return nil, err
+	}
+
+	resolved := make([]Column, len(columns))
+	for i, c := range columns {
+		if c.Unit != "" {
+			if c.Field == timeField {
+				return nil, errors.New("column '" + c.Header + "' is mapped to " + timeField + ", which has no unit")
+			}
+			target, ok := targets[c.Field]
+			if !ok {
+				return nil, errors.New("column '" + c.Header + "' has unit '" + c.Unit + "', but field '" + c.Field + "' has none")
+			}
+			factor, offset, err := convert(c.Unit, target, step)
+			if err != nil {
+				return nil, errors.New("column '" + c.Header + "': " + err.Error())
+			}
+			log.WithField("header", c.Header).WithField("field", c.Field).WithField("from", c.Unit).WithField("to", target).Debug("converting units")
+			c.Factor, c.Offset = factor*c.Factor, factor*c.Offset+offset
+		}
+		resolved[i] = c
+	}
+
+	return &CSVFormat{
+		Time:    timeParser,
+		Columns: resolved,
+	}, nil
+}
+
+// NewCSVFormatFromConfig returns the CSVFormat as configured by this package's
+// config paths.
+func NewCSVFormatFromConfig() (*CSVFormat, error) {
+	t, err := NewTimeParserFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	columns, err := NewColumnsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	targets, err := NewUnitsFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewCSVFormat(t, columns, targets, config.Viper.GetDuration(PathStepSize))
+}
+
+// timeSequence returns a new TimeSequence for a single time-column.
+func (f *CSVFormat) timeSequence() *TimeSequence {
+	if f == nil {
+		return (*TimeParser)(nil).Sequence()
+	}
+	return f.Time.Sequence()
+}
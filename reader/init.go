@@ -1,10 +1,8 @@
 package reader
 
 import (
-	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
+	"io"
+	"regexp"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,22 +10,43 @@ import (
 	"github.com/theMomax/openefs/models/production/weather"
 	timeutils "github.com/theMomax/openefs/utils/time"
 
-	"github.com/gocarina/gocsv"
 	"github.com/theMomax/openefs-csv-feeder/config"
 )
 
 // Config paths
 const (
-	PathWeatherBasePath = "reader.weatherbasepath"
-	PathProductionPath  = "reader.productionpath"
-	PathStepSize        = "reader.productionstepsize"
-	PathStepAmount      = "reader.productionsteps"
+	PathWeatherBasePath        = "reader.weatherbasepath"
+	PathWeatherPattern         = "reader.weatherpattern"
+	PathWeatherPath            = "reader.weatherpath"
+	PathWeatherHorizonColumn   = "reader.weatherhorizoncolumn"
+	PathWeatherIssueTimeColumn = "reader.weatherissuetimecolumn"
+	PathWeatherValidTimeColumn = "reader.weathervalidtimecolumn"
+	PathProductionPath         = "reader.productionpath"
+	PathStepSize               = "reader.productionstepsize"
+	PathStepAmount             = "reader.productionsteps"
+	PathStreaming              = "reader.streaming"
+	PathStreamingWindow        = "reader.streamingwindow"
 )
 
 func init() {
 	config.RootCtx.PersistentFlags().StringP(PathWeatherBasePath, "w", ".", "the folder, where the weather-input-files are located")
 	config.Viper.BindPFlag(PathWeatherBasePath, config.RootCtx.PersistentFlags().Lookup(PathWeatherBasePath))
 
+	config.RootCtx.PersistentFlags().String(PathWeatherPattern, DefaultWeatherPattern, "the regular expression weather-input-file-names must match (the forecast-distance is taken from the capture-group named 'horizon')")
+	config.Viper.BindPFlag(PathWeatherPattern, config.RootCtx.PersistentFlags().Lookup(PathWeatherPattern))
+
+	config.RootCtx.PersistentFlags().String(PathWeatherPath, "", "a single file containing weather-input-data for all forecast-distances (overrides "+PathWeatherBasePath+")")
+	config.Viper.BindPFlag(PathWeatherPath, config.RootCtx.PersistentFlags().Lookup(PathWeatherPath))
+
+	config.RootCtx.PersistentFlags().String(PathWeatherHorizonColumn, "Horizon", "the column holding the forecast-distance in the file specified by "+PathWeatherPath)
+	config.Viper.BindPFlag(PathWeatherHorizonColumn, config.RootCtx.PersistentFlags().Lookup(PathWeatherHorizonColumn))
+
+	config.RootCtx.PersistentFlags().String(PathWeatherIssueTimeColumn, "", "the column holding the issue-time in the file specified by "+PathWeatherPath+" (if set, the forecast-distance is derived from issue- and valid-time instead of "+PathWeatherHorizonColumn+")")
+	config.Viper.BindPFlag(PathWeatherIssueTimeColumn, config.RootCtx.PersistentFlags().Lookup(PathWeatherIssueTimeColumn))
+
+	config.RootCtx.PersistentFlags().String(PathWeatherValidTimeColumn, "ValidTime", "the column holding the valid-time in the file specified by "+PathWeatherPath+" (only used together with "+PathWeatherIssueTimeColumn+")")
+	config.Viper.BindPFlag(PathWeatherValidTimeColumn, config.RootCtx.PersistentFlags().Lookup(PathWeatherValidTimeColumn))
+
 	config.RootCtx.PersistentFlags().StringP(PathProductionPath, "p", ".", "the file containing production-input-data")
 	config.Viper.BindPFlag(PathProductionPath, config.RootCtx.PersistentFlags().Lookup(PathProductionPath))
 
@@ -36,6 +55,12 @@ func init() {
 
 	config.RootCtx.PersistentFlags().Uint(PathStepAmount, 120, "the amount of steps (as defined by "+PathStepSize+") required by the production-forecasting-model")
 	config.Viper.BindPFlag(PathStepAmount, config.RootCtx.PersistentFlags().Lookup(PathStepAmount))
+
+	config.RootCtx.PersistentFlags().Bool(PathStreaming, false, "read the input-files lazily instead of loading them into memory (the files must be sorted by time)")
+	config.Viper.BindPFlag(PathStreaming, config.RootCtx.PersistentFlags().Lookup(PathStreaming))
+
+	config.RootCtx.PersistentFlags().Duration(PathStreamingWindow, 24*time.Hour, "the duration for which already passed values are kept in memory when streaming (limits replacement of missing values)")
+	config.Viper.BindPFlag(PathStreamingWindow, config.RootCtx.PersistentFlags().Lookup(PathStreamingWindow))
 	config.OnInitialize(func() {
 		log = config.NewLogger()
 	})
@@ -53,6 +78,18 @@ type Reader struct {
 	latestWeatherData    map[time.Duration]*time.Time
 	forecastPoints       []time.Duration
 	round                func(t time.Time) time.Time
+	files                []string
+	productionFill       *Fill
+	weatherFill          *Fill
+	filled               map[string]map[string]uint
+	gaps                 map[string]uint
+
+	streaming        bool
+	window           time.Duration
+	evictedBefore    time.Time
+	productionCursor *productionCursor
+	weatherCursors   map[time.Duration]*weatherCursor
+	err              error
 }
 
 func NewReader(weatherBaseAddress string, productionAddress string, timestep time.Duration, stepAmount uint) (*Reader, error) {
@@ -63,191 +100,277 @@ func NewReader(weatherBaseAddress string, productionAddress string, timestep tim
 		"stepAmount":         stepAmount,
 	}).Info("creating new reader...")
 
-	round := func(date time.Time) time.Time {
-		r := timeutils.Round(date, timestep)
-		if r.Unix() != date.Unix() {
-			log.WithField("actual", date).WithField("rounded", r).Trace("rounded input-timestamp")
-		}
-		return r
+	ws, err := NewCSVWeatherSource(weatherBaseAddress, regexp.MustCompile(DefaultWeatherPattern), timestep, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	wold, wlatest, forecastPoints, wd, err := readWeatherInput(weatherBaseAddress, round, stepAmount)
-	if err != nil {
+	return NewReaderFromSources(ws, NewCSVProductionSource(productionAddress, nil), timestep, stepAmount)
+}
+
+// NewReaderFromSources creates a Reader, that loads all data provided by the
+// given sources into memory.
+func NewReaderFromSources(weatherSource WeatherSource, productionSource ProductionSource, timestep time.Duration, stepAmount uint) (*Reader, error) {
+	r := newReader(timestep, stepAmount)
+	r.addFiles(weatherSource, productionSource)
+
+	if err := r.readWeatherInput(weatherSource); err != nil {
 		return nil, err
 	}
 
-	pold, platest, pd, err := readProductionInput(productionAddress, round)
-	if err != nil {
+	if err := r.readProductionInput(productionSource); err != nil {
 		return nil, err
 	}
 
-	return &Reader{
-		production:           pd,
-		oldestProductionData: pold,
-		latestProductionData: platest,
-		weather:              wd,
-		oldestWeatherData:    wold,
-		latestWeatherData:    wlatest,
-		forecastPoints:       forecastPoints,
-		productionTimestep:   timestep,
-		round:                round,
-	}, nil
+	return r, nil
 }
 
 func NewReaderFromConfig() (*Reader, error) {
-	return NewReader(config.Viper.GetString(PathWeatherBasePath), config.Viper.GetString(PathProductionPath), config.Viper.GetDuration(PathStepSize), config.Viper.GetUint(PathStepAmount))
+	format, err := NewCSVFormatFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	ws, err := NewWeatherSourceFromConfig(format)
+	if err != nil {
+		return nil, err
+	}
+	ps := NewProductionSourceFromConfig(format)
+	productionFill, weatherFill, err := NewFillFromConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var r *Reader
+	if config.Viper.GetBool(PathStreaming) {
+		r, err = NewStreamingReader(ws, ps, config.Viper.GetDuration(PathStepSize), config.Viper.GetUint(PathStepAmount), config.Viper.GetDuration(PathStreamingWindow))
+	} else {
+		r, err = NewReaderFromSources(ws, ps, config.Viper.GetDuration(PathStepSize), config.Viper.GetUint(PathStepAmount))
+	}
+	if err != nil {
+		return nil, err
+	}
+	r.productionFill, r.weatherFill = productionFill, weatherFill
+	return r, nil
 }
 
-func readWeatherInput(weatherBasePath string, round func(time.Time) time.Time, stepAmount uint) (oldest, latest map[time.Duration]*time.Time, forecastPoints []time.Duration, data map[time.Duration]map[time.Time]*weather.Data, err error) {
-	log.Info("reading weather data...")
+// NewWeatherSourceFromConfig returns the WeatherSource as configured by this
+// package's config paths. The input-files are interpreted as described by
+// format.
+func NewWeatherSourceFromConfig(format *CSVFormat) (WeatherSource, error) {
+	if path := config.Viper.GetString(PathWeatherPath); path != "" && config.Viper.GetString(PathWeatherIssueTimeColumn) != "" {
+		log.WithField("path", path).WithField("issueTimeColumn", config.Viper.GetString(PathWeatherIssueTimeColumn)).WithField("validTimeColumn", config.Viper.GetString(PathWeatherValidTimeColumn)).Info("using single weather-input-file")
+		return NewIssueTimeCSVWeatherSource(path, config.Viper.GetString(PathWeatherIssueTimeColumn), config.Viper.GetString(PathWeatherValidTimeColumn), format), nil
+	} else if path != "" {
+		log.WithField("path", path).WithField("horizonColumn", config.Viper.GetString(PathWeatherHorizonColumn)).Info("using single weather-input-file")
+		return NewLongCSVWeatherSource(path, config.Viper.GetString(PathWeatherHorizonColumn), format), nil
+	}
 
-	type fileInfo struct {
-		path     string
-		isHourly bool
+	pattern, err := regexp.Compile(config.Viper.GetString(PathWeatherPattern))
+	if err != nil {
+		return nil, err
 	}
-	files := make(map[time.Duration]fileInfo, 0)
+	log.WithField("basePath", config.Viper.GetString(PathWeatherBasePath)).WithField("pattern", pattern.String()).Info("using weather-input-folder")
+	return NewCSVWeatherSource(config.Viper.GetString(PathWeatherBasePath), pattern, config.Viper.GetDuration(PathStepSize), format)
+}
 
-	err = filepath.Walk(weatherBasePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		log.WithField("filepath", path).Trace("found candidate-file")
-		if !info.IsDir() && strings.HasSuffix(info.Name(), ".csv") {
-			if !strings.HasPrefix(info.Name(), "forecast_") || !strings.HasSuffix(info.Name(), "_ahead.csv") {
-				log.WithField("filepath", path).Warning("possible input-file does not match pattern 'forecast_*(h|d)_ahead.csv', where * is a non-negative integer (prefix/suffix not matching)")
-				return nil
-			}
-			durationDescription := strings.TrimSuffix(strings.TrimPrefix(info.Name(), "forecast_"), "_ahead.csv")
-			var multiplier time.Duration
-			switch durationDescription[len(durationDescription)-1] {
-			case 'h':
-				multiplier = time.Hour
-			case 'd':
-				multiplier = 24 * time.Hour
-			default:
-				log.WithField("filepath", path).WithField("unit", string(durationDescription[len(durationDescription)-1])).Warning("possible input-file does not match pattern 'forecast_*(h|d)_ahead.csv', where * is a non-negative integer (illegal duration-unit)")
-				return nil
-			}
+// NewProductionSourceFromConfig returns the ProductionSource as configured by
+// this package's config paths. The input-file is interpreted as described by
+// format.
+func NewProductionSourceFromConfig(format *CSVFormat) ProductionSource {
+	log.WithField("path", config.Viper.GetString(PathProductionPath)).Info("using production-input-file")
+	return NewCSVProductionSource(config.Viper.GetString(PathProductionPath), format)
+}
 
-			number, err := strconv.Atoi(durationDescription[:len(durationDescription)-1])
-			if err != nil {
-				log.WithField("filepath", path).WithError(err).Warning("possible input-file does not match pattern 'forecast_*(h|d)_ahead.csv', where * is a non-negative integer (illegal duration-number)")
-				return nil
-			}
+// Files returns the paths of all input-files, as far as the Reader's sources
+// implement FileSource.
+func (r *Reader) Files() []string {
+	return r.files
+}
 
-			files[time.Duration(number)*multiplier] = fileInfo{
-				path:     path,
-				isHourly: multiplier == time.Hour,
-			}
-			return nil
+func (r *Reader) addFiles(sources ...interface{}) {
+	for _, s := range sources {
+		if fs, ok := s.(FileSource); ok {
+			r.files = append(r.files, fs.Files()...)
 		}
-		return nil
-	})
-	if err != nil {
-		return nil, nil, nil, nil, err
 	}
+}
 
-	wd := make(map[time.Duration]map[time.Time]*weather.Data)
-	oldm := make(map[time.Duration]*time.Time)
-	latestm := make(map[time.Duration]*time.Time)
+// Err returns the first error, that occurred while reading input lazily.
+func (r *Reader) Err() error {
+	return r.err
+}
 
-	for d, fi := range files {
-		log.WithField("filepath", fi.path).WithField("isHourly", fi.isHourly).WithField("duration_ahead", d).Debug("reading next weather-input-file")
-		f, err := os.OpenFile(fi.path, os.O_RDONLY, os.ModePerm)
-		if err != nil {
-			return nil, nil, nil, nil, err
+// Close releases all input-streams held by a streaming Reader.
+func (r *Reader) Close() error {
+	var err error
+	if r.productionCursor != nil {
+		err = r.productionCursor.stream.Close()
+	}
+	for _, c := range r.weatherCursors {
+		if cerr := c.stream.Close(); err == nil {
+			err = cerr
 		}
-		defer f.Close()
+	}
+	return err
+}
 
-		type weatherCSVData struct {
-			*weather.Data
-			Time time.Time `csv:"Time"`
+func newReader(timestep time.Duration, stepAmount uint) *Reader {
+	round := func(date time.Time) time.Time {
+		r := timeutils.Round(date, timestep)
+		if r.Unix() != date.Unix() {
+			log.WithField("actual", date).WithField("rounded", r).Trace("rounded input-timestamp")
 		}
-		ws := []*weatherCSVData{}
+		return r
+	}
+
+	forecastPoints := make([]time.Duration, 0)
+	maxForecastDistance := time.Duration(stepAmount-1) * timestep
+	for i := 0 * time.Second; i <= maxForecastDistance; i += timestep {
+		forecastPoints = append(forecastPoints, i)
+	}
+
+	return &Reader{
+		production:         make(map[time.Time]*production.Data),
+		weather:            make(map[time.Duration]map[time.Time]*weather.Data),
+		oldestWeatherData:  make(map[time.Duration]*time.Time),
+		latestWeatherData:  make(map[time.Duration]*time.Time),
+		forecastPoints:     forecastPoints,
+		productionTimestep: timestep,
+		round:              round,
+		productionFill:     &Fill{Strategy: FillLOCF},
+		weatherFill:        &Fill{Strategy: FillLOCF},
+		filled:             make(map[string]map[string]uint),
+		gaps:               make(map[string]uint),
+	}
+}
+
+func (r *Reader) readWeatherInput(source WeatherSource) error {
+	log.Info("reading weather data...")
+
+	if mixed, ok := source.(MixedWeatherSource); ok {
+		return r.readMixedWeatherInput(mixed)
+	}
+
+	horizons, err := source.Horizons()
+	if err != nil {
+		return err
+	}
 
-		if err := gocsv.UnmarshalFile(f, &ws); err != nil {
-			return nil, nil, nil, nil, err
+	for _, d := range horizons {
+		s, err := source.OpenWeather(d)
+		if err != nil {
+			return err
 		}
-		wd[d] = make(map[time.Time]*weather.Data)
-
-		var oldest *time.Time
-		var latest *time.Time
-
-		log.WithField("amount", len(ws)).Debug("elements extracted")
-
-		for _, w := range ws {
-			log.WithField("element", w).Trace()
-			r := round(w.Time)
-			if wd[d][r] == nil || fi.isHourly {
-				wd[d][r] = w.Data
-				if oldest == nil || oldest.Sub(r) > 0 {
-					oldest = &r
-				}
-				if latest == nil || latest.Sub(r) < 0 {
-					latest = &r
-				}
+
+		r.weather[d] = make(map[time.Time]*weather.Data)
+
+		for {
+			t, w, err := s.Next()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				s.Close()
+				return err
 			}
+			r.putWeather(d, t, w)
 		}
-		log.WithField("oldest", oldest).WithField("latest", latest).Debug("file processed")
-		oldm[d] = oldest
-		latestm[d] = latest
+		if err := s.Close(); err != nil {
+			return err
+		}
+		log.WithField("oldest", r.oldestWeatherData[d]).WithField("latest", r.latestWeatherData[d]).Debug("file processed")
 	}
 
-	forecastPoints = make([]time.Duration, 0)
-	maxForecastDistance := time.Duration(stepAmount-1) * config.Viper.GetDuration(PathStepSize)
-	for i := 0 * time.Second; i <= maxForecastDistance; i += time.Hour {
-		forecastPoints = append(forecastPoints, i)
+	r.logWeatherInput()
+	return nil
+}
+
+// readMixedWeatherInput reads the records of all forecast-distances in a
+// single pass.
+func (r *Reader) readMixedWeatherInput(source MixedWeatherSource) error {
+	s, err := source.OpenMixedWeather()
+	if err != nil {
+		return err
 	}
 
-	log.WithField("amount_forecastPoints", len(forecastPoints)).Info("weather-processing complete")
-	for _, d := range forecastPoints {
-		log.WithField("forecastPoint", d).WithField("backed", wd[d] != nil).Debug()
+	for {
+		t, d, w, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			s.Close()
+			return err
+		}
+		if r.weather[d] == nil {
+			r.weather[d] = make(map[time.Time]*weather.Data)
+		}
+		r.putWeather(d, t, w)
+	}
+	if err := s.Close(); err != nil {
+		return err
 	}
+	log.WithField("amount", len(r.weather)).Debug("found forecast-distances")
 
-	return oldm, latestm, forecastPoints, wd, nil
+	r.logWeatherInput()
+	return nil
 }
 
-func readProductionInput(productionAddress string, round func(time.Time) time.Time) (oldest, latest *time.Time, data map[time.Time]*production.Data, err error) {
+func (r *Reader) logWeatherInput() {
+	log.WithField("amount_forecastPoints", len(r.forecastPoints)).Info("weather-processing complete")
+	for _, d := range r.forecastPoints {
+		log.WithField("forecastPoint", d).WithField("backed", r.weather[d] != nil).Debug()
+	}
+}
+
+func (r *Reader) readProductionInput(source ProductionSource) error {
 	log.Info("reading production data...")
 
-	f, err := os.OpenFile(productionAddress, os.O_RDONLY, os.ModePerm)
+	s, err := source.OpenProduction()
 	if err != nil {
-		return nil, nil, nil, err
+		return err
 	}
-	defer f.Close()
+	defer s.Close()
 
-	type prodCSVData struct {
-		*production.Data
-		Time time.Time `csv:"Time"`
+	for {
+		t, p, err := s.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		r.putProduction(t, p)
 	}
-	ps := []*prodCSVData{}
 
-	if err := gocsv.UnmarshalFile(f, &ps); err != nil {
-		return nil, nil, nil, err
+	if len(r.production) == 0 {
+		log.Warning("no production data found")
+	} else {
+		log.WithField("amount", len(r.production)).Info("production-processing complete")
 	}
 
-	pd := make(map[time.Time]*production.Data)
+	return nil
+}
 
-	for _, p := range ps {
-		r := round(p.Time)
-		if pd[r] == nil {
-			pd[r] = p.Data
-			if oldest == nil || oldest.Sub(r) > 0 {
-				oldest = &r
-			}
-			if latest == nil || latest.Sub(r) < 0 {
-				latest = &r
-			}
-		} else {
-			log.WithField("timestep", r).Warn("conflicting production input")
-		}
+func (r *Reader) putWeather(distance time.Duration, date time.Time, data *weather.Data) {
+	t := r.round(date)
+	r.weather[distance][t] = data
+	if oldest := r.oldestWeatherData[distance]; oldest == nil || oldest.Sub(t) > 0 {
+		r.oldestWeatherData[distance] = &t
 	}
-
-	if len(pd) == 0 {
-		log.Warning("no production data found")
-	} else {
-		log.WithField("amount", len(pd)).Info("production-processing complete")
+	if latest := r.latestWeatherData[distance]; latest == nil || latest.Sub(t) < 0 {
+		r.latestWeatherData[distance] = &t
 	}
+}
 
-	return oldest, latest, pd, nil
+func (r *Reader) putProduction(date time.Time, data *production.Data) {
+	t := r.round(date)
+	if r.production[t] != nil {
+		log.WithField("timestep", t).Warn("conflicting production input")
+		return
+	}
+	r.production[t] = data
+	if r.oldestProductionData == nil || r.oldestProductionData.Sub(t) > 0 {
+		r.oldestProductionData = &t
+	}
+	if r.latestProductionData == nil || r.latestProductionData.Sub(t) < 0 {
+		r.latestProductionData = &t
+	}
 }
@@ -1,6 +1,7 @@
 package reader
 
 import (
+	"errors"
 	"time"
 
 	"github.com/theMomax/openefs/models/production"
@@ -12,9 +13,13 @@ type Iterator struct {
 	isEmpty bool
 	curr    time.Time
 	end     time.Time
+	until   time.Time
 }
 
-func (r *Reader) NewIterator(start ...time.Time) *Iterator {
+// NewIterator returns an Iterator over all time-steps backed by input-data.
+// The optional bounds narrow the window: bounds[0] is the first time-step
+// (if later than the oldest input-value), bounds[1] the exclusive end.
+func (r *Reader) NewIterator(bounds ...time.Time) *Iterator {
 	if r.oldestProductionData == nil || r.oldestWeatherData == nil {
 		return &Iterator{
 			isEmpty: true,
@@ -28,8 +33,21 @@ func (r *Reader) NewIterator(start ...time.Time) *Iterator {
 		}
 	}
 
-	if len(start) == 1 && s.Sub(start[0]) < 0 {
-		s = start[0]
+	if len(bounds) > 0 && s.Sub(bounds[0]) < 0 {
+		s = bounds[0]
+	}
+
+	var until time.Time
+	if len(bounds) > 1 {
+		until = bounds[1]
+	}
+
+	if r.streaming {
+		return &Iterator{
+			reader: r,
+			curr:   s,
+			until:  until,
+		}
 	}
 
 	end := *r.latestProductionData
@@ -43,24 +61,60 @@ func (r *Reader) NewIterator(start ...time.Time) *Iterator {
 		reader: r,
 		curr:   s,
 		end:    end,
+		until:  until,
 	}
 }
 
-func (r *Reader) ForEach(productionCallback func(time.Time, *production.Data), weatherCallback func([]time.Time, []*weather.Data), start ...time.Time) {
-	it := r.NewIterator(start...)
+func (r *Reader) ForEach(productionCallback func(time.Time, *production.Data), weatherCallback func([]time.Time, []*weather.Data), bounds ...time.Time) {
+	it := r.NewIterator(bounds...)
 	for it.HasNext() {
 		t, p, w := it.Next(true, true)
 		productionCallback(t, p)
-		times := make([]time.Time, len(w))
-		for i := range times {
-			times[i] = t.Add(time.Duration(i) * time.Hour)
-		}
-		weatherCallback(times, w)
+		weatherCallback(r.ForecastTimes(t), w)
+	}
+}
+
+// ForecastTimes returns the times the values returned by ReadWeatherForecast
+// for the given date belong to.
+func (r *Reader) ForecastTimes(date time.Time) []time.Time {
+	times := make([]time.Time, len(r.forecastPoints))
+	for i := range times {
+		times[i] = date.Add(r.forecastPoints[i])
 	}
+	return times
+}
+
+// Current returns the time-step returned by the next call to Next.
+func (i *Iterator) Current() time.Time {
+	return i.curr
+}
+
+// Seek moves the Iterator to the time-step closest to date. Iterators of a
+// streaming Reader cannot be moved backwards.
+func (i *Iterator) Seek(date time.Time) error {
+	if i.isEmpty {
+		return nil
+	}
+	date = i.reader.round(date)
+	if i.reader.streaming && date.Before(i.curr) {
+		return errors.New("cannot seek backwards when streaming")
+	}
+	i.curr = date
+	return nil
 }
 
 func (i *Iterator) HasNext() bool {
-	return !i.isEmpty && i.curr.Sub(i.end) <= 0
+	if i.isEmpty {
+		return false
+	}
+	if !i.until.IsZero() && !i.curr.Before(i.until) {
+		return false
+	}
+	if i.reader.streaming {
+		i.reader.advance(i.curr)
+		return i.reader.hasDataFrom(i.curr)
+	}
+	return i.curr.Sub(i.end) <= 0
 }
 
 // Next returns the next production- and weather-data. If there is none the
@@ -0,0 +1,237 @@
+package reader
+
+import (
+	"errors"
+	"io"
+	"os"
+	"time"
+
+	"github.com/gocarina/gocsv"
+	"github.com/theMomax/openefs/models/production/weather"
+)
+
+// LongCSVWeatherSource is a WeatherSource reading from a single file, that
+// holds the forecasts for all distances. The distance of each row is either
+// read from a dedicated column or derived from the row's issue- and valid-time.
+type LongCSVWeatherSource struct {
+	path            string
+	horizonColumn   string
+	issueTimeColumn string
+	validTimeColumn string
+	format          *CSVFormat
+}
+
+// NewLongCSVWeatherSource returns a WeatherSource for the given file. The
+// horizonColumn's values must be durations like '36h' or '1h30m'.
+func NewLongCSVWeatherSource(path string, horizonColumn string, format *CSVFormat) *LongCSVWeatherSource {
+	return &LongCSVWeatherSource{
+		path:          path,
+		horizonColumn: horizonColumn,
+		format:        format,
+	}
+}
+
+// NewIssueTimeCSVWeatherSource returns a WeatherSource for the given file. Each
+// row's forecast-distance is its valid-time minus its issue-time. Both columns
+// are parsed as described by format.
+func NewIssueTimeCSVWeatherSource(path string, issueTimeColumn, validTimeColumn string, format *CSVFormat) *LongCSVWeatherSource {
+	return &LongCSVWeatherSource{
+		path:            path,
+		issueTimeColumn: issueTimeColumn,
+		validTimeColumn: validTimeColumn,
+		format:          format,
+	}
+}
+
+// Horizons implements WeatherSource. It has to read through the whole file.
+func (s *LongCSVWeatherSource) Horizons() ([]time.Duration, error) {
+	stream, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	defer stream.Close()
+
+	seen := make(map[time.Duration]bool)
+	horizons := make([]time.Duration, 0)
+	for {
+		_, d, _, err := stream.next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if !seen[d] {
+			seen[d] = true
+			horizons = append(horizons, d)
+		}
+	}
+	log.WithField("filepath", s.path).WithField("amount", len(horizons)).Debug("found forecast-distances")
+	return horizons, nil
+}
+
+// Files implements FileSource.
+func (s *LongCSVWeatherSource) Files() []string {
+	return []string{s.path}
+}
+
+// OpenWeather implements WeatherSource. Every stream reads through the whole
+// file and skips all rows of other distances. Readers, that do not stream,
+// use OpenMixedWeather instead.
+func (s *LongCSVWeatherSource) OpenWeather(distance time.Duration) (WeatherStream, error) {
+	stream, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	stream.distance = distance
+	return stream, nil
+}
+
+// OpenMixedWeather implements MixedWeatherSource.
+func (s *LongCSVWeatherSource) OpenMixedWeather() (MixedWeatherStream, error) {
+	stream, err := s.open()
+	if err != nil {
+		return nil, err
+	}
+	return longCSVMixedWeatherStream{stream}, nil
+}
+
+func (s *LongCSVWeatherSource) open() (*longCSVWeatherStream, error) {
+	f, um, sc, err := openCSV(s.path, weatherCSVData{}, s.format)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range s.columns() {
+		if !contains(um.MismatchedHeaders, c) {
+			f.Close()
+			return nil, errors.New("weather-input-file '" + s.path + "' has no column '" + c + "'")
+		}
+	}
+
+	return &longCSVWeatherStream{
+		source:    s,
+		file:      f,
+		um:        um,
+		time:      s.format.timeSequence(),
+		issueTime: s.format.timeSequence(),
+		validTime: s.format.timeSequence(),
+		scaling:   sc,
+	}, nil
+}
+
+func (s *LongCSVWeatherSource) columns() []string {
+	if s.issueTimeColumn != "" {
+		return []string{s.issueTimeColumn, s.validTimeColumn}
+	}
+	return []string{s.horizonColumn}
+}
+
+// record extracts the valid-time and forecast-distance of the stream's next
+// row. Rows with an illegal forecast-distance are logged and skipped.
+func (s *longCSVWeatherStream) record(w weatherCSVData, unmatched map[string]string) (time.Time, time.Duration, error) {
+	source := s.source
+	if source.issueTimeColumn == "" {
+		t, err := parseTime(s.time, source.path, w.Time)
+		if err != nil {
+			return time.Time{}, 0, err
+		}
+		d, err := parseDistance(unmatched[source.horizonColumn])
+		if err != nil {
+			s.skip(w, err)
+			return time.Time{}, 0, errSkipRow
+		}
+		return t, d, nil
+	}
+
+	issued, err := parseTime(s.issueTime, source.path, unmatched[source.issueTimeColumn])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	valid, err := parseTime(s.validTime, source.path, unmatched[source.validTimeColumn])
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	if valid.Before(issued) {
+		s.skip(w, errors.New("valid-time precedes issue-time"))
+		return time.Time{}, 0, errSkipRow
+	}
+	return valid, valid.Sub(issued), nil
+}
+
+// skip logs, that the row w is skipped due to an illegal forecast-distance.
+func (s *longCSVWeatherStream) skip(w weatherCSVData, err error) {
+	log.WithField("filepath", s.source.path).WithField("element", w).WithError(err).Warning("skipping row with illegal forecast-distance")
+}
+
+type longCSVWeatherStream struct {
+	source    *LongCSVWeatherSource
+	file      *os.File
+	um        *gocsv.Unmarshaller
+	time      *TimeSequence
+	issueTime *TimeSequence
+	validTime *TimeSequence
+	scaling   scaling
+	distance  time.Duration
+}
+
+func (s *longCSVWeatherStream) Next() (time.Time, *weather.Data, error) {
+	for {
+		t, d, w, err := s.next()
+		if err != nil {
+			return time.Time{}, nil, err
+		}
+		if d == s.distance {
+			return t, w, nil
+		}
+	}
+}
+
+func (s *longCSVWeatherStream) next() (time.Time, time.Duration, *weather.Data, error) {
+	for {
+		v, unmatched, err := s.um.ReadUnmatched()
+		if err != nil {
+			return time.Time{}, 0, nil, err
+		}
+		w := v.(weatherCSVData)
+
+		t, d, err := s.record(w, unmatched)
+		if err == errSkipRow {
+			continue
+		} else if err != nil {
+			return time.Time{}, 0, nil, err
+		}
+		s.scaling.apply(w)
+		return t, d, w.Data, nil
+	}
+}
+
+func (s *longCSVWeatherStream) Close() error {
+	return s.file.Close()
+}
+
+// longCSVMixedWeatherStream yields the rows of all distances.
+type longCSVMixedWeatherStream struct {
+	*longCSVWeatherStream
+}
+
+func (s longCSVMixedWeatherStream) Next() (time.Time, time.Duration, *weather.Data, error) {
+	return s.next()
+}
+
+// parseDistance accepts descriptions as understood by parseHorizon as well as
+// time.ParseDuration.
+func parseDistance(description string) (time.Duration, error) {
+	if d, _, err := parseHorizon(description); err == nil {
+		return d, nil
+	}
+	return time.ParseDuration(description)
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
@@ -7,7 +7,7 @@ import (
 )
 
 func (r *Reader) ReadProduction(date time.Time, replace ...bool) *model.Data {
-	if len(r.production) == 0 || r.oldestProductionData.Sub(date) > 0 {
+	if r.oldestProductionData == nil || r.oldestProductionData.Sub(date) > 0 || r.isEvicted(date) {
 		return nil
 	}
 
@@ -15,7 +15,27 @@ func (r *Reader) ReadProduction(date time.Time, replace ...bool) *model.Data {
 		return val
 	}
 	if len(replace) == 1 && replace[0] {
-		return r.ReadProduction(date.Add(-1*r.productionTimestep), true)
+		if v := r.fill(r.productionFill, r.productionSeries(), date); v != nil {
+			return v.(*model.Data)
+		}
+		r.gap(seriesProduction, date)
 	}
 	return nil
 }
+
+func (r *Reader) productionSeries() series {
+	return series{
+		name: seriesProduction,
+		lookup: func(t time.Time) interface{} {
+			if v := r.production[r.round(t)]; v != nil {
+				return v
+			}
+			return nil
+		},
+		oldest: r.oldestProductionData,
+		latest: r.latestProductionData,
+		zero: func() interface{} {
+			return &model.Data{}
+		},
+	}
+}
@@ -0,0 +1,60 @@
+package reader
+
+import (
+	"time"
+
+	"github.com/theMomax/openefs/models/production"
+	"github.com/theMomax/openefs/models/production/weather"
+)
+
+// ProductionSource provides the production-input-data consumed by a Reader.
+type ProductionSource interface {
+	// OpenProduction returns a stream over all production-records held by the
+	// source.
+	OpenProduction() (ProductionStream, error)
+}
+
+// ProductionStream iterates over production-records. Next returns io.EOF once
+// the stream is exhausted.
+type ProductionStream interface {
+	Next() (time.Time, *production.Data, error)
+	Close() error
+}
+
+// WeatherSource provides the weather-forecast-input-data consumed by a Reader.
+type WeatherSource interface {
+	// Horizons returns the forecast-distances the source holds data for.
+	Horizons() ([]time.Duration, error)
+	// OpenWeather returns a stream over all records, that were forecasted the
+	// given distance ahead.
+	OpenWeather(distance time.Duration) (WeatherStream, error)
+}
+
+// WeatherStream iterates over weather-records. Next returns io.EOF once the
+// stream is exhausted.
+type WeatherStream interface {
+	Next() (time.Time, *weather.Data, error)
+	Close() error
+}
+
+// MixedWeatherSource is optionally implemented by WeatherSources, that hold
+// the records of all forecast-distances in a single stream. It allows reading
+// all of them in a single pass instead of once per distance.
+type MixedWeatherSource interface {
+	// OpenMixedWeather returns a stream over the records of all
+	// forecast-distances.
+	OpenMixedWeather() (MixedWeatherStream, error)
+}
+
+// MixedWeatherStream iterates over weather-records of any forecast-distance.
+// Next returns io.EOF once the stream is exhausted.
+type MixedWeatherStream interface {
+	Next() (time.Time, time.Duration, *weather.Data, error)
+	Close() error
+}
+
+// FileSource is optionally implemented by sources, that read from files.
+type FileSource interface {
+	// Files returns the paths of all files the source reads from.
+	Files() []string
+}
@@ -0,0 +1,187 @@
+package reader
+
+import (
+	"io"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/theMomax/openefs/models/production"
+	"github.com/theMomax/openefs/models/production/weather"
+)
+
+type productionCursor struct {
+	stream ProductionStream
+	time   time.Time
+	data   *production.Data
+	done   bool
+}
+
+type weatherCursor struct {
+	stream WeatherStream
+	time   time.Time
+	data   *weather.Data
+	done   bool
+}
+
+// orderedStream is optionally implemented by WeatherStreams, that can save
+// memory if their input is known to be sorted by time.
+type orderedStream interface {
+	assumeOrdered()
+}
+
+// NewStreamingReader creates a Reader, that pulls records from the given
+// sources lazily while being iterated. The sources must provide their records
+// sorted by time. Values older than window (relative to the Iterator's current
+// position) are dropped and thus no longer available for replacement.
+func NewStreamingReader(weatherSource WeatherSource, productionSource ProductionSource, timestep time.Duration, stepAmount uint, window time.Duration) (*Reader, error) {
+	log.WithFields(logrus.Fields{
+		"timestep":   timestep,
+		"stepAmount": stepAmount,
+		"window":     window,
+	}).Info("creating new streaming reader...")
+
+	r := newReader(timestep, stepAmount)
+	r.streaming = true
+	r.window = window
+	r.weatherCursors = make(map[time.Duration]*weatherCursor)
+	r.addFiles(weatherSource, productionSource)
+
+	horizons, err := weatherSource.Horizons()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, d := range horizons {
+		s, err := weatherSource.OpenWeather(d)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		if o, ok := s.(orderedStream); ok {
+			o.assumeOrdered()
+		}
+		c := &weatherCursor{stream: s}
+		r.weatherCursors[d] = c
+		r.weather[d] = make(map[time.Time]*weather.Data)
+		if err := c.next(); err != nil {
+			r.Close()
+			return nil, err
+		}
+		if !c.done {
+			t := r.round(c.time)
+			r.oldestWeatherData[d] = &t
+		}
+	}
+
+	s, err := productionSource.OpenProduction()
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	r.productionCursor = &productionCursor{stream: s}
+	if err := r.productionCursor.next(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	if !r.productionCursor.done {
+		t := r.round(r.productionCursor.time)
+		r.oldestProductionData = &t
+	} else {
+		log.Warning("no production data found")
+	}
+
+	return r, nil
+}
+
+// advance loads all records required for reading data at the given date and
+// drops those, which have fallen out of the window.
+func (r *Reader) advance(date time.Time) {
+	if !r.streaming {
+		return
+	}
+
+	if evict := date.Add(-r.window); evict.After(r.evictedBefore) {
+		r.evictedBefore = evict
+		for t := range r.production {
+			if t.Before(evict) {
+				delete(r.production, t)
+			}
+		}
+		for _, m := range r.weather {
+			for t := range m {
+				if t.Before(evict) {
+					delete(m, t)
+				}
+			}
+		}
+	}
+
+	c := r.productionCursor
+	for !c.done && !r.round(c.time).After(date) {
+		if !r.isEvicted(r.round(c.time)) {
+			r.putProduction(c.time, c.data)
+		}
+		r.check(c.next())
+	}
+
+	until := date.Add(r.forecastPoints[len(r.forecastPoints)-1])
+	for d, c := range r.weatherCursors {
+		for !c.done && !r.round(c.time).After(until) {
+			if !r.isEvicted(r.round(c.time)) {
+				r.putWeather(d, c.time, c.data)
+			}
+			r.check(c.next())
+		}
+	}
+}
+
+// hasDataFrom returns whether there may be any data at or after the given
+// date.
+func (r *Reader) hasDataFrom(date time.Time) bool {
+	if !r.productionCursor.done || (r.latestProductionData != nil && r.latestProductionData.Sub(date) >= 0) {
+		return true
+	}
+	for d, c := range r.weatherCursors {
+		if !c.done || (r.latestWeatherData[d] != nil && r.latestWeatherData[d].Sub(date) >= 0) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Reader) isEvicted(date time.Time) bool {
+	return r.streaming && date.Before(r.evictedBefore)
+}
+
+func (r *Reader) check(err error) {
+	if err != nil && r.err == nil {
+		log.WithError(err).Error("reading input failed")
+		r.err = err
+	}
+}
+
+func (c *productionCursor) next() error {
+	t, p, err := c.stream.Next()
+	if err != nil {
+		c.done = true
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	c.time, c.data = t, p
+	return nil
+}
+
+func (c *weatherCursor) next() error {
+	t, w, err := c.stream.Next()
+	if err != nil {
+		c.done = true
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	c.time, c.data = t, w
+	return nil
+}
@@ -0,0 +1,222 @@
+package reader
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathTimeFormat    = "reader.timeformat"
+	PathTimeZone      = "reader.timezone"
+	PathTimeAmbiguous = "reader.timeambiguous"
+	PathTimeMissing   = "reader.timemissing"
+)
+
+// Named timestamp-formats
+const (
+	TimeFormatRFC3339   = "rfc3339"
+	TimeFormatUnix      = "unix"
+	TimeFormatUnixMilli = "unixmilli"
+	TimeFormatExcel     = "excel"
+)
+
+// Policies for local times, that occur twice or not at all due to DST.
+const (
+	TimeEarlier    = "earlier"
+	TimeLater      = "later"
+	TimeOccurrence = "occurrence"
+	TimeShift      = "shift"
+	TimeSkip       = "skip"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathTimeFormat, TimeFormatRFC3339, "the format of all time-columns: one of "+TimeFormatRFC3339+", "+TimeFormatUnix+", "+TimeFormatUnixMilli+", "+TimeFormatExcel+" (serial days) or a Go time-layout like '2006-01-02 15:04'")
+	config.Viper.BindPFlag(PathTimeFormat, config.RootCtx.PersistentFlags().Lookup(PathTimeFormat))
+
+	config.RootCtx.PersistentFlags().String(PathTimeZone, "UTC", "the time-zone (e.g. 'Europe/Berlin' or 'Local') of timestamps, that do not state their offset")
+	config.Viper.BindPFlag(PathTimeZone, config.RootCtx.PersistentFlags().Lookup(PathTimeZone))
+
+	config.RootCtx.PersistentFlags().String(PathTimeAmbiguous, TimeEarlier, "how to handle local times, that occur twice when DST ends: "+TimeEarlier+", "+TimeLater+", "+TimeOccurrence+" (the earlier offset, unless the time repeats the previous row's, which requires each column to be ordered by time) or "+TimeSkip+" (the row)")
+	config.Viper.BindPFlag(PathTimeAmbiguous, config.RootCtx.PersistentFlags().Lookup(PathTimeAmbiguous))
+
+	config.RootCtx.PersistentFlags().String(PathTimeMissing, TimeShift, "how to handle local times, that do not exist when DST starts: "+TimeShift+" (forward by the gap) or "+TimeSkip+" (the row)")
+	config.Viper.BindPFlag(PathTimeMissing, config.RootCtx.PersistentFlags().Lookup(PathTimeMissing))
+}
+
+// errSkipRow is returned by TimeParser.Parse if a row is to be skipped.
+var errSkipRow = errors.New("row skipped")
+
+// excelEpoch is day 0 of Excel's serial dates (accounting for Excel's
+// fictional 1900-02-29).
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// excelMax is the serial date of 9999-12-31, the last day supported by Excel.
+const excelMax = 2958465
+
+// TimeParser parses the values of time-columns.
+type TimeParser struct {
+	format    string
+	location  *time.Location
+	ambiguous string
+	missing   string
+}
+
+// NewTimeParser returns a TimeParser for the given format. Local times, that
+// do not state their offset, are interpreted in the given location.
+func NewTimeParser(format string, location *time.Location, ambiguous, missing string) (*TimeParser, error) {
+	if format == "" {
+		return nil, errors.New("empty " + PathTimeFormat)
+	}
+	if ambiguous != TimeEarlier && ambiguous != TimeLater && ambiguous != TimeOccurrence && ambiguous != TimeSkip {
+		return nil, errors.New("unknown " + PathTimeAmbiguous + " '" + ambiguous + "'")
+	}
+	if missing != TimeShift && missing != TimeSkip {
+		return nil, errors.New("unknown " + PathTimeMissing + " '" + missing + "'")
+	}
+	return &TimeParser{
+		format:    format,
+		location:  location,
+		ambiguous: ambiguous,
+		missing:   missing,
+	}, nil
+}
+
+// NewTimeParserFromConfig calls NewTimeParser using the values configured by
+// this package's config paths.
+func NewTimeParserFromConfig() (*TimeParser, error) {
+	location, err := time.LoadLocation(config.Viper.GetString(PathTimeZone))
+	if err != nil {
+		return nil, err
+	}
+	return NewTimeParser(config.Viper.GetString(PathTimeFormat), location, config.Viper.GetString(PathTimeAmbiguous), config.Viper.GetString(PathTimeMissing))
+}
+
+// Parse parses value. A nil TimeParser expects RFC3339. If the row holding
+// value is to be skipped, errSkipRow is returned. As Parse does not know the
+// previous value, TimeOccurrence always picks the earlier offset. Use a
+// TimeSequence instead.
+func (p *TimeParser) Parse(value string) (time.Time, error) {
+	return p.parse(value, time.Time{})
+}
+
+// parse parses value, which follows previous within its column. previous is
+// zero for the first value.
+func (p *TimeParser) parse(value string, previous time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if p == nil {
+		return time.Parse(time.RFC3339, value)
+	}
+
+	switch p.format {
+	case TimeFormatRFC3339:
+		return time.Parse(time.RFC3339, value)
+	case TimeFormatUnix:
+		unix, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(unix, 0), nil
+	case TimeFormatUnixMilli:
+		milli, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(milli/1000, milli%1000*int64(time.Millisecond)), nil
+	case TimeFormatExcel:
+		days, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		if days < 0 || days > excelMax {
+			return time.Time{}, errors.New("serial date out of range")
+		}
+		// rounded to milliseconds, as serial dates are imprecise
+		ms := math.Round(days * 24 * float64(time.Hour/time.Millisecond))
+		return p.local(excelEpoch.Add(time.Duration(ms)*time.Millisecond), previous)
+	}
+
+	t, err := time.Parse(p.format, value)
+	if err != nil || hasZone(p.format) {
+		return t, err
+	}
+	return p.local(t, previous)
+}
+
+// local interprets the wall-clock of t (given in UTC) in the TimeParser's
+// location. Ambiguous and missing times are handled as configured.
+func (p *TimeParser) local(t time.Time, previous time.Time) (time.Time, error) {
+	// offsets in effect around t, i.e. before and after a DST-transition
+	_, before := t.Add(-24 * time.Hour).In(p.location).Zone()
+	_, after := t.Add(24 * time.Hour).In(p.location).Zone()
+
+	candidates := make([]time.Time, 0, 2)
+	for _, offset := range []int{before, after} {
+		c := t.Add(-time.Duration(offset) * time.Second)
+		if _, o := c.In(p.location).Zone(); o == offset && (len(candidates) == 0 || !candidates[0].Equal(c)) {
+			candidates = append(candidates, c)
+		}
+	}
+
+	switch {
+	case len(candidates) == 1:
+		return candidates[0].In(p.location), nil
+	case len(candidates) == 2:
+		log.WithField("time", t.Format("2006-01-02 15:04:05")).WithField("location", p.location).WithField("policy", p.ambiguous).Debug("ambiguous local time")
+		earlier, later := candidates[0], candidates[1]
+		if later.Before(earlier) {
+			earlier, later = later, earlier
+		}
+		switch p.ambiguous {
+		case TimeEarlier:
+			return earlier.In(p.location), nil
+		case TimeLater:
+			return later.In(p.location), nil
+		case TimeOccurrence:
+			// a time not advancing past the previous one repeats it
+			if !previous.IsZero() && !earlier.After(previous) {
+				return later.In(p.location), nil
+			}
+			return earlier.In(p.location), nil
+		}
+	default:
+		log.WithField("time", t.Format("2006-01-02 15:04:05")).WithField("location", p.location).WithField("policy", p.missing).Debug("missing local time")
+		if p.missing == TimeShift {
+			return t.Add(-time.Duration(before) * time.Second).In(p.location), nil
+		}
+	}
+	return time.Time{}, errSkipRow
+}
+
+// TimeSequence parses the values of a single time-column in the order they
+// occur, which TimeOccurrence depends on.
+type TimeSequence struct {
+	parser   *TimeParser
+	previous time.Time
+}
+
+// Sequence returns a new TimeSequence. A nil TimeParser expects RFC3339.
+func (p *TimeParser) Sequence() *TimeSequence {
+	return &TimeSequence{
+		parser: p,
+	}
+}
+
+// Parse parses the column's next value as described by TimeParser.Parse.
+func (s *TimeSequence) Parse(value string) (time.Time, error) {
+	t, err := s.parser.parse(value, s.previous)
+	if err == nil {
+		s.previous = t
+	}
+	return t, err
+}
+
+// hasZone returns true if the Go time-layout holds a time-zone or offset.
+func hasZone(layout string) bool {
+	return strings.Contains(layout, "MST") || strings.Contains(layout, "Z07") || strings.Contains(layout, "-07")
+}
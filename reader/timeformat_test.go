@@ -0,0 +1,190 @@
+package reader
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func init() {
+	// usually set up by config.OnInitialize
+	log = logrus.New()
+}
+
+func berlin(t *testing.T) *time.Location {
+	l, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("time-zone database not available: ", err)
+	}
+	return l
+}
+
+func utc(value string) time.Time {
+	t, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestTimeParserParse(t *testing.T) {
+	const layout = "2006-01-02 15:04"
+	berlin := berlin(t)
+
+	tests := []struct {
+		name      string
+		format    string
+		location  *time.Location
+		ambiguous string
+		missing   string
+		value     string
+		want      time.Time
+		err       error
+	}{
+		{"rfc3339", TimeFormatRFC3339, berlin, TimeEarlier, TimeShift, "2019-07-01T12:00:00+02:00", utc("2019-07-01T10:00:00Z"), nil},
+		{"layout summer", layout, berlin, TimeEarlier, TimeShift, "2019-07-01 12:00", utc("2019-07-01T10:00:00Z"), nil},
+		{"layout winter", layout, berlin, TimeEarlier, TimeShift, "2019-01-01 12:00", utc("2019-01-01T11:00:00Z"), nil},
+		{"layout with offset", layout + " -0700", berlin, TimeEarlier, TimeShift, "2019-10-27 02:30 +0000", utc("2019-10-27T02:30:00Z"), nil},
+		{"layout utc", layout, time.UTC, TimeSkip, TimeSkip, "2019-10-27 02:30", utc("2019-10-27T02:30:00Z"), nil},
+
+		{"before dst starts", layout, berlin, TimeEarlier, TimeShift, "2019-03-31 01:30", utc("2019-03-31T00:30:00Z"), nil},
+		{"missing shift", layout, berlin, TimeEarlier, TimeShift, "2019-03-31 02:30", utc("2019-03-31T01:30:00Z"), nil},
+		{"missing skip", layout, berlin, TimeEarlier, TimeSkip, "2019-03-31 02:30", time.Time{}, errSkipRow},
+		{"after dst starts", layout, berlin, TimeEarlier, TimeSkip, "2019-03-31 03:00", utc("2019-03-31T01:00:00Z"), nil},
+
+		{"ambiguous earlier", layout, berlin, TimeEarlier, TimeShift, "2019-10-27 02:30", utc("2019-10-27T00:30:00Z"), nil},
+		{"ambiguous later", layout, berlin, TimeLater, TimeShift, "2019-10-27 02:30", utc("2019-10-27T01:30:00Z"), nil},
+		{"ambiguous occurrence", layout, berlin, TimeOccurrence, TimeShift, "2019-10-27 02:30", utc("2019-10-27T00:30:00Z"), nil},
+		{"ambiguous skip", layout, berlin, TimeSkip, TimeShift, "2019-10-27 02:30", time.Time{}, errSkipRow},
+		{"after dst ends", layout, berlin, TimeSkip, TimeShift, "2019-10-27 03:00", utc("2019-10-27T02:00:00Z"), nil},
+
+		{"unix", TimeFormatUnix, berlin, TimeEarlier, TimeShift, "1561982400", utc("2019-07-01T12:00:00Z"), nil},
+		{"unix padded", TimeFormatUnix, berlin, TimeEarlier, TimeShift, " 1561982400 ", utc("2019-07-01T12:00:00Z"), nil},
+		{"unixmilli", TimeFormatUnixMilli, berlin, TimeEarlier, TimeShift, "1561982400123", utc("2019-07-01T12:00:00.123Z"), nil},
+
+		{"excel utc", TimeFormatExcel, time.UTC, TimeEarlier, TimeShift, "43647.5", utc("2019-07-01T12:00:00Z"), nil},
+		{"excel local", TimeFormatExcel, berlin, TimeEarlier, TimeShift, "43647.5", utc("2019-07-01T10:00:00Z"), nil},
+		{"excel imprecise", TimeFormatExcel, time.UTC, TimeEarlier, TimeShift, "43647.0416666667", utc("2019-07-01T01:00:00Z"), nil},
+		{"excel missing", TimeFormatExcel, berlin, TimeEarlier, TimeShift, "43555.1041666667", utc("2019-03-31T01:30:00Z"), nil},
+		{"excel ambiguous", TimeFormatExcel, berlin, TimeLater, TimeShift, "43765.1041666667", utc("2019-10-27T01:30:00Z"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewTimeParser(tt.format, tt.location, tt.ambiguous, tt.missing)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := p.Parse(tt.value)
+			if err != tt.err {
+				t.Fatalf("Parse(%q) returned error %v, want %v", tt.value, err, tt.err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Parse(%q) = %v, want %v", tt.value, got.UTC(), tt.want)
+			}
+		})
+	}
+}
+
+func TestTimeParserParseIllegal(t *testing.T) {
+	for _, tt := range []struct {
+		format string
+		value  string
+	}{
+		{TimeFormatRFC3339, "2019-07-01 12:00"},
+		{TimeFormatUnix, "1561982400.5"},
+		{TimeFormatUnixMilli, "abc"},
+		{TimeFormatExcel, "-1"},
+		{TimeFormatExcel, "1e10"},
+		{"2006-01-02 15:04", "2019-07-01T12:00:00Z"},
+	} {
+		p, err := NewTimeParser(tt.format, time.UTC, TimeEarlier, TimeShift)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := p.Parse(tt.value); err == nil || err == errSkipRow {
+			t.Errorf("Parse(%q) using %s returned error %v, want parse-error", tt.value, tt.format, err)
+		}
+	}
+}
+
+func TestNewTimeParserIllegal(t *testing.T) {
+	for _, tt := range []struct {
+		format, ambiguous, missing string
+	}{
+		{"", TimeEarlier, TimeShift},
+		{TimeFormatUnix, TimeShift, TimeShift},
+		{TimeFormatUnix, TimeEarlier, TimeEarlier},
+	} {
+		if _, err := NewTimeParser(tt.format, time.UTC, tt.ambiguous, tt.missing); err == nil {
+			t.Errorf("NewTimeParser(%q, %q, %q) succeeded", tt.format, tt.ambiguous, tt.missing)
+		}
+	}
+}
+
+func TestTimeSequenceOccurrence(t *testing.T) {
+	berlin := berlin(t)
+
+	tests := []struct {
+		name   string
+		format string
+		values []string
+		want   []string
+	}{
+		{
+			"quarter-hourly",
+			"2006-01-02 15:04",
+			[]string{"2019-10-27 01:45", "2019-10-27 02:00", "2019-10-27 02:15", "2019-10-27 02:30", "2019-10-27 02:45", "2019-10-27 02:00", "2019-10-27 02:15", "2019-10-27 02:30", "2019-10-27 02:45", "2019-10-27 03:00"},
+			[]string{"2019-10-26T23:45:00Z", "2019-10-27T00:00:00Z", "2019-10-27T00:15:00Z", "2019-10-27T00:30:00Z", "2019-10-27T00:45:00Z", "2019-10-27T01:00:00Z", "2019-10-27T01:15:00Z", "2019-10-27T01:30:00Z", "2019-10-27T01:45:00Z", "2019-10-27T02:00:00Z"},
+		},
+		{
+			"hourly",
+			"2006-01-02 15:04",
+			[]string{"2019-10-27 01:00", "2019-10-27 02:00", "2019-10-27 02:00", "2019-10-27 03:00"},
+			[]string{"2019-10-26T23:00:00Z", "2019-10-27T00:00:00Z", "2019-10-27T01:00:00Z", "2019-10-27T02:00:00Z"},
+		},
+		{
+			"repeated forecasts",
+			"2006-01-02 15:04",
+			[]string{"2019-10-27 02:00", "2019-10-27 02:00", "2019-10-27 01:00", "2019-10-27 02:00"},
+			[]string{"2019-10-27T00:00:00Z", "2019-10-27T01:00:00Z", "2019-10-26T23:00:00Z", "2019-10-27T00:00:00Z"},
+		},
+		{
+			"excel hourly",
+			TimeFormatExcel,
+			[]string{"43765.0416666667", "43765.0833333333", "43765.0833333333", "43765.125"},
+			[]string{"2019-10-26T23:00:00Z", "2019-10-27T00:00:00Z", "2019-10-27T01:00:00Z", "2019-10-27T02:00:00Z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewTimeParser(tt.format, berlin, TimeOccurrence, TimeShift)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := p.Sequence()
+			for i, v := range tt.values {
+				got, err := s.Parse(v)
+				if err != nil {
+					t.Fatalf("value %d: Parse(%q) returned error %v", i, v, err)
+				}
+				if want := utc(tt.want[i]); !got.Equal(want) {
+					t.Errorf("value %d: Parse(%q) = %v, want %v", i, v, got.UTC(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestNilTimeParser(t *testing.T) {
+	var p *TimeParser
+	got, err := p.Sequence().Parse("2019-07-01T12:00:00+02:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := utc("2019-07-01T10:00:00Z"); !got.Equal(want) {
+		t.Errorf("Parse = %v, want %v", got, want)
+	}
+}
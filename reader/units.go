@@ -0,0 +1,145 @@
+package reader
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/theMomax/openefs/models/production"
+	"github.com/theMomax/openefs/models/production/weather"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathUnits = "reader.units"
+)
+
+// Dimensions of units
+const (
+	dimensionPower       = "power"
+	dimensionEnergy      = "energy"
+	dimensionTemperature = "temperature"
+	dimensionSpeed       = "speed"
+)
+
+// unit converts values to its dimension's base unit by value*factor + offset.
+type unit struct {
+	dimension string
+	factor    float64
+	offset    float64
+}
+
+// units holds all known units. The base units are W, J, K and m/s.
+var units = map[string]unit{
+	"W":  {dimensionPower, 1, 0},
+	"kW": {dimensionPower, 1e3, 0},
+	"MW": {dimensionPower, 1e6, 0},
+
+	"J":   {dimensionEnergy, 1, 0},
+	"kJ":  {dimensionEnergy, 1e3, 0},
+	"MJ":  {dimensionEnergy, 1e6, 0},
+	"Wh":  {dimensionEnergy, 3600, 0},
+	"kWh": {dimensionEnergy, 3600e3, 0},
+	"MWh": {dimensionEnergy, 3600e6, 0},
+
+	"K":  {dimensionTemperature, 1, 0},
+	"C":  {dimensionTemperature, 1, 273.15},
+	"°C": {dimensionTemperature, 1, 273.15},
+	"F":  {dimensionTemperature, 5.0 / 9, 273.15 - 32*5.0/9},
+	"°F": {dimensionTemperature, 5.0 / 9, 273.15 - 32*5.0/9},
+
+	"m/s":  {dimensionSpeed, 1, 0},
+	"km/h": {dimensionSpeed, 1 / 3.6, 0},
+	"mph":  {dimensionSpeed, 0.44704, 0},
+	"kn":   {dimensionSpeed, 1852.0 / 3600, 0},
+}
+
+// perStep maps dimensions, that are accumulated over a time-step, to the
+// dimension of their average rate.
+var perStep = map[string]string{
+	dimensionEnergy: dimensionPower,
+}
+
+// DefaultUnits are the units openefs expects for the models' fields.
+var DefaultUnits = map[string]string{
+	"production":          "W",
+	"temperature":         "C",
+	"apparentTemperature": "C",
+	"dewPoint":            "C",
+	"windSpeed":           "m/s",
+	"windGust":            "m/s",
+}
+
+// NewUnitsFromConfig returns DefaultUnits overridden by the map from field to
+// unit configured under PathUnits, e.g.:
+//
+//	reader:
+//	  units:
+//	    production: kW
+func NewUnitsFromConfig() (map[string]string, error) {
+	configured := make(map[string]string)
+	if err := config.Viper.UnmarshalKey(PathUnits, &configured); err != nil {
+		return nil, errors.New("illegal " + PathUnits + ": " + err.Error())
+	}
+
+	targets := make(map[string]string, len(DefaultUnits))
+	for f, u := range DefaultUnits {
+		targets[f] = u
+	}
+	for key, u := range configured {
+		// viper does not preserve the case of keys
+		f, ok := fieldTag(key)
+		if !ok {
+			return nil, errors.New(PathUnits + " refers to unknown field '" + key + "'")
+		}
+		targets[f] = u
+	}
+	return targets, nil
+}
+
+// convert returns the factor and offset converting values given in unit from
+// to unit to. Values accumulated over a time-step are converted to their
+// average rate.
+func convert(from, to string, step time.Duration) (factor, offset float64, err error) {
+	f, ok := units[from]
+	if !ok {
+		return 0, 0, errors.New("unknown unit '" + from + "'")
+	}
+	t, ok := units[to]
+	if !ok {
+		return 0, 0, errors.New("unknown unit '" + to + "'")
+	}
+
+	perSecond := 1.0
+	if f.dimension != t.dimension {
+		if perStep[f.dimension] != t.dimension {
+			return 0, 0, errors.New("cannot convert '" + from + "' to '" + to + "'")
+		}
+		if step <= 0 {
+			return 0, 0, errors.New("converting '" + from + "' to '" + to + "' requires a positive step-size")
+		}
+		perSecond = 1 / step.Seconds()
+	}
+
+	// from -> base -> per second -> to
+	factor = f.factor * perSecond / t.factor
+	offset = (f.offset*perSecond - t.offset) / t.factor
+	return factor, offset, nil
+}
+
+// fieldTag returns the csv-tag of the models' float-field matching name
+// case-insensitively.
+func fieldTag(name string) (string, bool) {
+	for _, t := range []reflect.Type{reflect.TypeOf(production.Data{}), reflect.TypeOf(weather.Data{})} {
+		for i := 0; i < t.NumField(); i++ {
+			tag := t.Field(i).Tag.Get("csv")
+			if tag != "" && strings.EqualFold(tag, name) && t.Field(i).Type.Kind() == reflect.Float64 {
+				return tag, true
+			}
+		}
+	}
+	return "", false
+}
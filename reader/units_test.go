@@ -0,0 +1,116 @@
+package reader
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func almostEqual(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		from, to string
+		step     time.Duration
+		factor   float64
+		offset   float64
+	}{
+		{"W", "W", 0, 1, 0},
+		{"kW", "W", 0, 1e3, 0},
+		{"W", "MW", 0, 1e-6, 0},
+		{"kWh", "W", time.Hour, 1e3, 0},
+		{"kWh", "W", 15 * time.Minute, 4e3, 0},
+		{"Wh", "kW", 15 * time.Minute, 4e-3, 0},
+		{"MJ", "W", time.Hour, 1e6 / 3600, 0},
+		{"°F", "C", 0, 5.0 / 9, -32 * 5.0 / 9},
+		{"F", "K", 0, 5.0 / 9, 273.15 - 32*5.0/9},
+		{"C", "F", 0, 1.8, 32},
+		{"K", "C", 0, 1, -273.15},
+		{"km/h", "m/s", 0, 1 / 3.6, 0},
+		{"kn", "km/h", 0, 1.852, 0},
+		{"mph", "m/s", 0, 0.44704, 0},
+	}
+
+	for _, tt := range tests {
+		factor, offset, err := convert(tt.from, tt.to, tt.step)
+		if err != nil {
+			t.Errorf("convert(%s, %s, %v) returned error %v", tt.from, tt.to, tt.step, err)
+			continue
+		}
+		if !almostEqual(factor, tt.factor) || !almostEqual(offset, tt.offset) {
+			t.Errorf("convert(%s, %s, %v) = %v, %v, want %v, %v", tt.from, tt.to, tt.step, factor, offset, tt.factor, tt.offset)
+		}
+	}
+}
+
+func TestConvertIllegal(t *testing.T) {
+	tests := []struct {
+		from, to string
+		step     time.Duration
+	}{
+		{"kWh", "W", 0},
+		{"W", "kWh", time.Hour},
+		{"kWh", "m/s", time.Hour},
+		{"C", "W", time.Hour},
+		{"PS", "W", 0},
+		{"W", "PS", 0},
+		{"W/m2", "W", 0},
+	}
+
+	for _, tt := range tests {
+		if _, _, err := convert(tt.from, tt.to, tt.step); err == nil {
+			t.Errorf("convert(%s, %s, %v) succeeded", tt.from, tt.to, tt.step)
+		}
+	}
+}
+
+func TestNewCSVFormatUnits(t *testing.T) {
+	tests := []struct {
+		name   string
+		column Column
+		step   time.Duration
+		value  float64
+		want   float64
+	}{
+		{"unit only", Column{Header: "pv_kw", Field: "production", Factor: 1, Unit: "kW"}, time.Hour, 1.5, 1500},
+		{"energy per step", Column{Header: "pv_kwh", Field: "production", Factor: 1, Unit: "kWh"}, 15 * time.Minute, 0.25, 1000},
+		{"factor and offset", Column{Header: "pv", Field: "production", Factor: 2, Offset: 1, Unit: "kWh"}, 15 * time.Minute, 0.5, 8000},
+		{"scaled temperature", Column{Header: "temp_dF", Field: "temperature", Factor: 0.1, Unit: "°F"}, time.Hour, 500, 10},
+		{"kelvin", Column{Header: "temp_K", Field: "temperature", Factor: 1, Unit: "K"}, time.Hour, 273.15, 0},
+		{"speed", Column{Header: "wind_kmh", Field: "windSpeed", Factor: 1, Unit: "km/h"}, time.Hour, 36, 10},
+		{"no unit", Column{Header: "pv", Field: "production", Factor: 2, Offset: 1}, time.Hour, 3, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewCSVFormat(nil, []Column{tt.column}, DefaultUnits, tt.step)
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := f.Columns[0]
+			if got := tt.value*c.Factor + c.Offset; !almostEqual(got, tt.want) {
+				t.Errorf("%v converts to %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewCSVFormatUnitsIllegal(t *testing.T) {
+	tests := []struct {
+		name   string
+		column Column
+	}{
+		{"time", Column{Header: "t", Field: timeField, Factor: 1, Unit: "s"}},
+		{"field without unit", Column{Header: "h", Field: "humidity", Factor: 1, Unit: "%"}},
+		{"incompatible", Column{Header: "pv", Field: "production", Factor: 1, Unit: "km/h"}},
+		{"unknown field", Column{Header: "x", Field: "irradiance", Factor: 1, Unit: "W"}},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewCSVFormat(nil, []Column{tt.column}, DefaultUnits, time.Hour); err == nil {
+			t.Errorf("%s: NewCSVFormat succeeded", tt.name)
+		}
+	}
+}
@@ -7,21 +7,51 @@ import (
 )
 
 func (r *Reader) ReadWeather(date time.Time, distance time.Duration, replace ...bool) *model.Data {
+	val, missing := r.readWeather(date, distance, len(replace) == 1 && replace[0])
+	if missing {
+		r.gap(seriesWeather, date)
+	}
+	return val
+}
+
+// readWeather returns the value forecasted distance ahead of date. missing is
+// true if the value could not be replaced although requested.
+func (r *Reader) readWeather(date time.Time, distance time.Duration, replace bool) (val *model.Data, missing bool) {
 	if r.oldestWeatherData[distance] == nil || r.weather[distance] == nil {
-		return nil
+		return nil, false
 	}
 
-	if len(r.production) == 0 || r.oldestWeatherData[distance].Sub(date) > 0 {
-		return nil
+	if r.oldestProductionData == nil || r.oldestWeatherData[distance].Sub(date) > 0 || r.isEvicted(date) {
+		return nil, false
 	}
 
 	if val := r.weather[distance][r.round(date)]; val != nil {
-		return val
+		return val, false
+	}
+	if replace {
+		if v := r.fill(r.weatherFill, r.weatherSeries(distance), date); v != nil {
+			return v.(*model.Data), false
+		}
+		return nil, true
 	}
-	if len(replace) == 1 && replace[0] {
-		return r.ReadWeather(date.Add(-1*r.productionTimestep), distance, true)
+	return nil, false
+}
+
+func (r *Reader) weatherSeries(distance time.Duration) series {
+	return series{
+		name: seriesWeather,
+		lookup: func(t time.Time) interface{} {
+			if v := r.weather[distance][r.round(t)]; v != nil {
+				return v
+			}
+			return nil
+		},
+		oldest: r.oldestWeatherData[distance],
+		latest: r.latestWeatherData[distance],
+		zero: func() interface{} {
+			return &model.Data{}
+		},
 	}
-	return nil
 }
 
 func (r *Reader) ReadWeatherForecast(date time.Time, replace ...bool) []*model.Data {
@@ -31,11 +61,17 @@ func (r *Reader) ReadWeatherForecast(date time.Time, replace ...bool) []*model.D
 	vals := make([]*model.Data, len(r.forecastPoints))
 	for i, d := range r.forecastPoints {
 		t := date.Add(d)
-		vals[i] = r.ReadWeather(t, d, replaceByOlderTimestamp)
-		d -= time.Hour
+		var missing, m bool
+		vals[i], missing = r.readWeather(t, d, replaceByOlderTimestamp)
+		d -= r.productionTimestep
 		for vals[i] == nil && d >= 0 && replaceByMoreRecentForecast {
-			vals[i] = r.ReadWeather(t, d, replaceByOlderTimestamp)
-			d -= time.Hour
+			vals[i], m = r.readWeather(t, d, replaceByOlderTimestamp)
+			missing = missing || m
+			d -= r.productionTimestep
+		}
+		// counted once, no matter how many forecasts were tried
+		if vals[i] == nil && missing {
+			r.gap(seriesWeather, t)
 		}
 	}
 	return vals
@@ -0,0 +1,126 @@
+package writer
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathAuthBearerToken = "writer.auth.bearertoken"
+	PathAuthUsername    = "writer.auth.username"
+	PathAuthPassword    = "writer.auth.password"
+	PathAuthHeaders     = "writer.auth.headers"
+)
+
+// Prefixes for values, that are to be looked up elsewhere
+const (
+	envPrefix  = "env:"
+	filePrefix = "file:"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathAuthBearerToken, "", "bearer token sent with every request (use 'env:NAME' or 'file:PATH' to read it from an environment-variable or file)")
+	config.Viper.BindPFlag(PathAuthBearerToken, config.RootCtx.PersistentFlags().Lookup(PathAuthBearerToken))
+
+	config.RootCtx.PersistentFlags().String(PathAuthUsername, "", "username for basic authentication (supports 'env:NAME' and 'file:PATH')")
+	config.Viper.BindPFlag(PathAuthUsername, config.RootCtx.PersistentFlags().Lookup(PathAuthUsername))
+
+	config.RootCtx.PersistentFlags().String(PathAuthPassword, "", "password for basic authentication (supports 'env:NAME' and 'file:PATH')")
+	config.Viper.BindPFlag(PathAuthPassword, config.RootCtx.PersistentFlags().Lookup(PathAuthPassword))
+
+	// not bound, as viper cannot read string-array-flags (see NewAuthFromConfig)
+	config.RootCtx.PersistentFlags().StringArray(PathAuthHeaders, []string{}, "additional header 'Name: value' sent with every request (the value supports 'env:NAME' and 'file:PATH'; may be repeated)")
+}
+
+// Auth holds the credentials added to every request sent by an HTTPSink.
+type Auth struct {
+	BearerToken string
+	Username    string
+	Password    string
+	Headers     http.Header
+}
+
+// NewAuthFromConfig resolves all credentials configured by this package's
+// config paths.
+func NewAuthFromConfig() (*Auth, error) {
+	a := &Auth{
+		Headers: make(http.Header),
+	}
+
+	var err error
+	if a.BearerToken, err = resolve(config.Viper.GetString(PathAuthBearerToken)); err != nil {
+		return nil, err
+	}
+	if a.Username, err = resolve(config.Viper.GetString(PathAuthUsername)); err != nil {
+		return nil, err
+	}
+	if a.Password, err = resolve(config.Viper.GetString(PathAuthPassword)); err != nil {
+		return nil, err
+	}
+	if a.BearerToken != "" && (a.Username != "" || a.Password != "") {
+		return nil, errors.New(PathAuthBearerToken + " and basic authentication are mutually exclusive")
+	}
+
+	headers := config.Viper.GetStringSlice(PathAuthHeaders)
+	if flags := config.RootCtx.PersistentFlags(); flags.Changed(PathAuthHeaders) {
+		if headers, err = flags.GetStringArray(PathAuthHeaders); err != nil {
+			return nil, err
+		}
+	}
+	for _, h := range headers {
+		parts := strings.SplitN(h, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, errors.New("illegal header '" + h + "', expected 'Name: value'")
+		}
+		value, err := resolve(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		a.Headers.Add(strings.TrimSpace(parts[0]), value)
+	}
+	return a, nil
+}
+
+func (a *Auth) apply(req *http.Request) {
+	if a == nil {
+		return
+	}
+	for name, values := range a.Headers {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	if a.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+a.BearerToken)
+	} else if a.Username != "" || a.Password != "" {
+		req.SetBasicAuth(a.Username, a.Password)
+	}
+}
+
+// resolve returns the environment-variable's or file's content if value is
+// prefixed by 'env:' or 'file:' respectively.
+func resolve(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, envPrefix):
+		name := strings.TrimPrefix(value, envPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", errors.New("environment-variable '" + name + "' is not set")
+		}
+		return v, nil
+	case strings.HasPrefix(value, filePrefix):
+		b, err := ioutil.ReadFile(strings.TrimPrefix(value, filePrefix))
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return value, nil
+	}
+}
@@ -0,0 +1,196 @@
+package writer
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// BatchSink groups production- and weather-requests into batch-requests
+// holding an array of {timestamp, data}. If openefs does not support the
+// batch-route for a kind of request (http.StatusNotFound or
+// http.StatusMethodNotAllowed), the requests are passed on one by one. The
+// requests of any other failed batch are passed on one by one as well, so
+// failures are handled per request by the wrapped Sink.
+//
+// Requests of other kinds flush all pending requests before being passed on,
+// so the order between kinds is preserved. This is required, as openefs must
+// not receive a time-step's data before the mock-time reached it. Thus a
+// batch never holds more than the requests sent between two mock-time
+// updates, e.g. a single time-step's weather-values during a run.
+//
+// Errors of requests flushed in the background are returned by the next call
+// to Send or Close.
+type BatchSink struct {
+	next        Sink
+	address     string
+	size        uint
+	pending     []*Request
+	unsupported map[string]bool
+	err         error
+	m           sync.Mutex
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// batchItem is a single element of a batch-request's body.
+type batchItem struct {
+	Timestamp int64           `json:"timestamp"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// NewBatchSink creates a BatchSink sending batches of up to size requests to
+// the openefs instance at address. Pending requests are flushed at least
+// every interval.
+func NewBatchSink(next Sink, address string, size uint, interval time.Duration) *BatchSink {
+	s := &BatchSink{
+		next:        next,
+		address:     address,
+		size:        size,
+		unsupported: make(map[string]bool),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.done)
+		if interval <= 0 {
+			<-s.stop
+			return
+		}
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-t.C:
+				s.m.Lock()
+				if err := s.flush(); err != nil && s.err == nil {
+					s.err = err
+				}
+				s.m.Unlock()
+			}
+		}
+	}()
+	return s
+}
+
+// Send implements Sink.
+func (s *BatchSink) Send(r *Request) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if err := s.takeErr(); err != nil {
+		return err
+	}
+
+	if r.Batch || (r.Kind != KindProduction && r.Kind != KindWeather) {
+		if err := s.flush(); err != nil {
+			return err
+		}
+		return s.next.Send(r)
+	}
+
+	s.pending = append(s.pending, r)
+	if uint(len(s.pending)) >= s.size {
+		return s.flush()
+	}
+	return nil
+}
+
+// Flush sends all pending requests.
+func (s *BatchSink) Flush() error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if err := s.takeErr(); err != nil {
+		return err
+	}
+	return s.flush()
+}
+
+// Close implements Sink.
+func (s *BatchSink) Close() error {
+	close(s.stop)
+	<-s.done
+
+	err := s.Flush()
+	if cerr := s.next.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (s *BatchSink) takeErr() error {
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// flush sends all pending requests, grouping consecutive requests of the
+// same kind. s.m must be held.
+func (s *BatchSink) flush() error {
+	pending := s.pending
+	s.pending = nil
+
+	for len(pending) > 0 {
+		n := 1
+		for n < len(pending) && uint(n) < s.size && pending[n].Kind == pending[0].Kind {
+			n++
+		}
+		if err := s.send(pending[:n]); err != nil {
+			return err
+		}
+		pending = pending[n:]
+	}
+	return nil
+}
+
+func (s *BatchSink) send(requests []*Request) error {
+	kind := requests[0].Kind
+	if !s.unsupported[kind] && len(requests) > 1 {
+		items := make([]batchItem, len(requests))
+		for i, r := range requests {
+			items[i] = batchItem{
+				Timestamp: r.Timestamp,
+				Data:      r.Body,
+			}
+		}
+		b, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+
+		log.WithField("kind", kind).WithField("amount", len(requests)).Trace("trying to send batch...")
+		err = s.next.Send(&Request{
+			Kind:      kind,
+			Timestamp: requests[0].Timestamp,
+			Method:    http.MethodPost,
+			Endpoint:  batchEndpoint(s.address, kind),
+			Body:      b,
+			Batch:     true,
+		})
+		var we *WriteError
+		if err == nil {
+			return nil
+		} else if errors.As(err, &we) && (we.StatusCode == http.StatusNotFound || we.StatusCode == http.StatusMethodNotAllowed) {
+			log.WithField("kind", kind).WithField("status", we.Status).Warning("batch-route not supported, falling back to single requests")
+			s.unsupported[kind] = true
+		} else {
+			log.WithError(err).WithField("kind", kind).Warning("batch failed, falling back to single requests")
+		}
+	}
+
+	for _, r := range requests {
+		if err := s.next.Send(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func batchEndpoint(address string, kind string) string {
+	return address + "/v1/input/" + kind + "/batch/"
+}
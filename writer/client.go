@@ -0,0 +1,63 @@
+package writer
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathHTTPTimeout         = "writer.http.timeout"
+	PathHTTPMaxIdleConns    = "writer.http.maxidleconns"
+	PathHTTPKeepAlive       = "writer.http.keepalive"
+	PathHTTPIdleConnTimeout = "writer.http.idleconntimeout"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().Duration(PathHTTPTimeout, 30*time.Second, "the time limit for a single request including reading the response (unlimited if 0)")
+	config.Viper.BindPFlag(PathHTTPTimeout, config.RootCtx.PersistentFlags().Lookup(PathHTTPTimeout))
+
+	config.RootCtx.PersistentFlags().Int(PathHTTPMaxIdleConns, 16, "the maximum number of idle connections kept open per host")
+	config.Viper.BindPFlag(PathHTTPMaxIdleConns, config.RootCtx.PersistentFlags().Lookup(PathHTTPMaxIdleConns))
+
+	config.RootCtx.PersistentFlags().Bool(PathHTTPKeepAlive, true, "reuse connections for multiple requests")
+	config.Viper.BindPFlag(PathHTTPKeepAlive, config.RootCtx.PersistentFlags().Lookup(PathHTTPKeepAlive))
+
+	config.RootCtx.PersistentFlags().Duration(PathHTTPIdleConnTimeout, 90*time.Second, "the duration after which idle connections are closed")
+	config.Viper.BindPFlag(PathHTTPIdleConnTimeout, config.RootCtx.PersistentFlags().Lookup(PathHTTPIdleConnTimeout))
+}
+
+// NewHTTPClient returns a client dedicated to talking to openefs.
+func NewHTTPClient(timeout time.Duration, maxIdleConns int, keepAlive bool, idleConnTimeout time.Duration, tlsConfig *tls.Config) *http.Client {
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			DialContext: (&net.Dialer{
+				Timeout:   30 * time.Second,
+				KeepAlive: 30 * time.Second,
+			}).DialContext,
+			MaxIdleConns:          maxIdleConns,
+			MaxIdleConnsPerHost:   maxIdleConns,
+			IdleConnTimeout:       idleConnTimeout,
+			DisableKeepAlives:     !keepAlive,
+			TLSClientConfig:       tlsConfig,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: time.Second,
+		},
+	}
+}
+
+// NewHTTPClientFromConfig calls NewHTTPClient using the values configured by
+// this package's config paths.
+func NewHTTPClientFromConfig() (*http.Client, error) {
+	tlsConfig, err := NewTLSConfigFromConfig()
+	if err != nil {
+		return nil, err
+	}
+	return NewHTTPClient(config.Viper.GetDuration(PathHTTPTimeout), config.Viper.GetInt(PathHTTPMaxIdleConns), config.Viper.GetBool(PathHTTPKeepAlive), config.Viper.GetDuration(PathHTTPIdleConnTimeout), tlsConfig), nil
+}
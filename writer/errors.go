@@ -0,0 +1,49 @@
+package writer
+
+import (
+	"strconv"
+	"time"
+)
+
+// WriteError is returned by HTTPSink if a request could not be sent to
+// openefs. Either StatusCode is set (openefs responded with an unexpected
+// status) or Err (no response was received).
+type WriteError struct {
+	Kind       string
+	Timestamp  time.Time
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+	Err        error
+}
+
+func (e *WriteError) Error() string {
+	msg := "sending " + e.Kind + " for " + strconv.FormatInt(e.Timestamp.Unix(), 10) + " to " + e.URL + " failed: "
+	if e.Err != nil {
+		return msg + e.Err.Error()
+	}
+	if e.Body == "" {
+		return msg + e.Status
+	}
+	return msg + e.Status + ": " + e.Body
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// Fields returns the WriteError's details for structured logging.
+func (e *WriteError) Fields() map[string]interface{} {
+	fields := map[string]interface{}{
+		"kind":      e.Kind,
+		"timestamp": e.Timestamp.Unix(),
+		"url":       e.URL,
+	}
+	if e.StatusCode != 0 {
+		fields["status"] = e.Status
+		fields["body"] = e.Body
+	}
+	return fields
+}
@@ -0,0 +1,116 @@
+package writer
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// HTTPSink sends requests to openefs.
+type HTTPSink struct {
+	client *http.Client
+	retry  *RetryPolicy
+	auth   *Auth
+}
+
+// NewHTTPSink returns a Sink using the given client and RetryPolicy. If auth
+// is not nil, its credentials are added to every request.
+func NewHTTPSink(client *http.Client, retry *RetryPolicy, auth *Auth) *HTTPSink {
+	return &HTTPSink{
+		client: client,
+		retry:  retry,
+		auth:   auth,
+	}
+}
+
+// Send implements Sink. Failed requests are repeated as defined by the
+// HTTPSink's RetryPolicy. While openefs is busy (http.StatusIMUsed), requests
+// are repeated every RetryPolicy.Base without limit.
+func (s *HTTPSink) Send(r *Request) error {
+	// failures counts the attempts limited by the RetryPolicy, i.e. all
+	// attempts but those answered by http.StatusIMUsed
+	var failures uint
+	for attempt := uint(1); ; attempt++ {
+		log.WithField("kind", r.Kind).WithField("endpoint", r.Endpoint).WithField("attempt", attempt).Trace("trying to send request...")
+		req, err := http.NewRequest(r.Method, r.Endpoint, bytes.NewReader(r.Body))
+		if err != nil {
+			return s.error(r, nil, "", err)
+		}
+		if r.Body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		s.auth.apply(req)
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			failures++
+			if s.retry.exhausted(failures) {
+				return s.error(r, nil, "", err)
+			}
+		} else {
+			body := consume(resp)
+			if resp.StatusCode == http.StatusOK {
+				return nil
+			}
+			if resp.StatusCode == http.StatusIMUsed {
+				log.WithField("kind", r.Kind).WithField("endpoint", r.Endpoint).WithField("attempt", attempt).WithField("delay", s.retry.Base).Debug("openefs is busy, retrying...")
+				time.Sleep(s.retry.Base)
+				continue
+			}
+			failures++
+			if !s.retry.retryable(resp.StatusCode) || s.retry.exhausted(failures) {
+				return s.error(r, resp, body, nil)
+			}
+		}
+
+		d := s.retry.delay(failures, resp)
+		l := log.WithField("kind", r.Kind).WithField("endpoint", r.Endpoint).WithField("attempt", attempt).WithField("delay", d)
+		if err != nil {
+			l = l.WithError(err)
+		} else {
+			l = l.WithField("status", resp.Status)
+		}
+		l.Warning("request failed, retrying...")
+		time.Sleep(d)
+	}
+}
+
+// error describes the failure to send r as WriteError.
+func (s *HTTPSink) error(r *Request, resp *http.Response, body string, err error) *WriteError {
+	e := &WriteError{
+		Kind:      r.Kind,
+		Timestamp: r.Time(),
+		URL:       r.Endpoint,
+		Body:      body,
+		Err:       err,
+	}
+	if resp != nil {
+		e.StatusCode = resp.StatusCode
+		e.Status = resp.Status
+	}
+	return e
+}
+
+// Close implements Sink.
+func (s *HTTPSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Limits for reading response-bodies
+const (
+	maxErrorBody = 4 << 10
+	maxDrain     = 64 << 10
+)
+
+// consume reads the beginning of the response's body, drains the rest (so the
+// connection can be reused) and closes it.
+func consume(resp *http.Response) string {
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrain))
+	return strings.TrimSpace(string(b))
+}
@@ -1,18 +1,42 @@
 package writer
 
 import (
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/sirupsen/logrus"
 	"github.com/theMomax/openefs-csv-feeder/config"
 )
 
 // Config paths
 const (
-	PathAddress = "writer.address"
+	PathAddress       = "writer.address"
+	PathDryRun        = "writer.dryrun"
+	PathRecord        = "writer.record"
+	PathConcurrency   = "writer.concurrency"
+	PathBatchSize     = "writer.batchsize"
+	PathFlushInterval = "writer.flushinterval"
 )
 
 func init() {
 	config.RootCtx.PersistentFlags().StringP(PathAddress, "a", "http://localhost:8080", "openefs server address")
 	config.Viper.BindPFlag(PathAddress, config.RootCtx.PersistentFlags().Lookup(PathAddress))
+
+	config.RootCtx.PersistentFlags().String("dry-run", "", "record all requests to the given JSONL-file instead of sending them")
+	config.Viper.BindPFlag(PathDryRun, config.RootCtx.PersistentFlags().Lookup("dry-run"))
+
+	config.RootCtx.PersistentFlags().String("record", "", "additionally record all requests to the given JSONL-file (may be replayed later)")
+	config.Viper.BindPFlag(PathRecord, config.RootCtx.PersistentFlags().Lookup("record"))
+
+	config.RootCtx.PersistentFlags().Uint(PathConcurrency, 1, "the maximum number of weather-requests sent in parallel")
+	config.Viper.BindPFlag(PathConcurrency, config.RootCtx.PersistentFlags().Lookup(PathConcurrency))
+
+	config.RootCtx.PersistentFlags().Uint(PathBatchSize, 1, "the maximum number of production- or weather-values sent in a single batch-request (batching is disabled if less than 2); every mock-time update flushes the pending values, so a batch never spans more than the values sent between two mock-time updates, e.g. the weather-values of a single time-step")
+	config.Viper.BindPFlag(PathBatchSize, config.RootCtx.PersistentFlags().Lookup(PathBatchSize))
+
+	config.RootCtx.PersistentFlags().Duration(PathFlushInterval, time.Second, "the maximum duration values are held back for batching (rarely reached during a run, as every mock-time update flushes the pending values)")
+	config.Viper.BindPFlag(PathFlushInterval, config.RootCtx.PersistentFlags().Lookup(PathFlushInterval))
 	config.OnInitialize(func() {
 		log = config.NewLogger()
 	})
@@ -21,17 +45,82 @@ func init() {
 var log *logrus.Logger
 
 type Writer struct {
+	address        string
 	prodaddress    string
 	weatheraddress string
+	sink           Sink
+	slots          chan struct{}
 }
 
-func NewWriter(address string) *Writer {
+// NewWriter creates a Writer, that sends up to concurrency requests in
+// parallel.
+func NewWriter(address string, sink Sink, concurrency uint) *Writer {
+	if concurrency == 0 {
+		concurrency = 1
+	}
 	return &Writer{
+		address:        address,
 		prodaddress:    address + "/v1/input/production/:unixtimestamp/",
 		weatheraddress: address + "/v1/input/weather/:unixtimestamp/",
+		sink:           sink,
+		slots:          make(chan struct{}, concurrency),
 	}
 }
 
-func NewWriterFromConfig() *Writer {
-	return NewWriter(config.Viper.GetString(PathAddress))
+func NewWriterFromConfig(sink Sink) *Writer {
+	return NewWriter(config.Viper.GetString(PathAddress), sink, config.Viper.GetUint(PathConcurrency))
+}
+
+// NewSinkFromConfig returns the Sink as configured by this package's config
+// paths.
+func NewSinkFromConfig() (Sink, error) {
+	var s Sink
+	if path := config.Viper.GetString(PathDryRun); path != "" {
+		log.WithField("path", path).Warning("dry-run enabled, requests are recorded instead of sent")
+		fs, err := NewFileSink(path)
+		if err != nil {
+			return nil, err
+		}
+		s = fs
+	} else {
+		auth, err := NewAuthFromConfig()
+		if err != nil {
+			return nil, err
+		}
+		client, err := NewHTTPClientFromConfig()
+		if err != nil {
+			return nil, err
+		}
+		s = NewHTTPSink(client, NewRetryPolicyFromConfig(), auth)
+	}
+
+	s, err := NewPolicySinkFromConfig(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if size := config.Viper.GetUint(PathBatchSize); size > 1 {
+		log.WithField("size", size).Info("batching enabled")
+		s = NewBatchSink(s, config.Viper.GetString(PathAddress), size, config.Viper.GetDuration(PathFlushInterval))
+	}
+
+	if path := config.Viper.GetString(PathRecord); path != "" {
+		log.WithField("path", path).Info("recording session")
+		return NewRecordingSink(s, path)
+	}
+	return s, nil
+}
+
+// BatchEndpoint returns the URL batches of data of the given kind are sent to.
+func (w *Writer) BatchEndpoint(kind string) string {
+	return batchEndpoint(w.address, kind)
+}
+
+// Endpoint returns the URL data of the given kind is sent to.
+func (w *Writer) Endpoint(kind string, date time.Time) string {
+	address := w.prodaddress
+	if kind == KindWeather {
+		address = w.weatheraddress
+	}
+	return strings.ReplaceAll(address, ":unixtimestamp", strconv.FormatInt(date.Unix(), 10))
 }
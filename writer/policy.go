@@ -0,0 +1,116 @@
+package writer
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathOnError    = "writer.onerror"
+	PathDeadLetter = "writer.deadletter"
+)
+
+// Policies for handling failed requests
+const (
+	OnErrorAbort      = "abort"
+	OnErrorSkip       = "skip"
+	OnErrorQuarantine = "quarantine"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathOnError, OnErrorAbort, "what to do if a request fails finally: '"+OnErrorAbort+"', '"+OnErrorSkip+"' (log and continue) or '"+OnErrorQuarantine+"' (write it to "+PathDeadLetter+" and continue); failed mock-time updates always abort")
+	config.Viper.BindPFlag(PathOnError, config.RootCtx.PersistentFlags().Lookup(PathOnError))
+
+	config.RootCtx.PersistentFlags().String(PathDeadLetter, "failed.jsonl", "JSONL-file quarantined requests are appended to")
+	config.Viper.BindPFlag(PathDeadLetter, config.RootCtx.PersistentFlags().Lookup(PathDeadLetter))
+}
+
+// PolicySink handles failures of the wrapped Sink by either skipping or
+// quarantining the failed request. Failed mock-time updates are returned
+// nevertheless, as continuing with the wrong time is pointless. Failed
+// batch-requests are returned as well, as BatchSink falls back to sending
+// their requests one by one.
+type PolicySink struct {
+	next       Sink
+	deadLetter *FileSink
+	m          sync.Mutex
+	failed     uint
+}
+
+// NewPolicySink returns a Sink applying the given policy. If policy is
+// OnErrorQuarantine, failed requests are appended to the file at
+// deadLetterPath. If policy is OnErrorAbort, next is returned unchanged.
+func NewPolicySink(next Sink, policy string, deadLetterPath string) (Sink, error) {
+	s := &PolicySink{
+		next: next,
+	}
+	switch policy {
+	case OnErrorAbort:
+		return next, nil
+	case OnErrorSkip:
+	case OnErrorQuarantine:
+		deadLetter, err := NewAppendingFileSink(deadLetterPath)
+		if err != nil {
+			return nil, err
+		}
+		s.deadLetter = deadLetter
+	default:
+		return nil, errors.New("unknown " + PathOnError + " '" + policy + "'")
+	}
+	return s, nil
+}
+
+// NewPolicySinkFromConfig calls NewPolicySink using the values configured by
+// this package's config paths.
+func NewPolicySinkFromConfig(next Sink) (Sink, error) {
+	return NewPolicySink(next, config.Viper.GetString(PathOnError), config.Viper.GetString(PathDeadLetter))
+}
+
+// Send implements Sink.
+func (s *PolicySink) Send(r *Request) error {
+	err := s.next.Send(r)
+	if err == nil || r.Kind == KindMockTime || r.Batch {
+		return err
+	}
+
+	l := log.WithError(err)
+	var we *WriteError
+	if errors.As(err, &we) {
+		l = l.WithFields(we.Fields())
+	}
+
+	s.m.Lock()
+	s.failed++
+	s.m.Unlock()
+
+	if s.deadLetter == nil {
+		l.Error("skipping failed request")
+		return nil
+	}
+	if derr := s.deadLetter.Send(r); derr != nil {
+		return derr
+	}
+	l.Error("quarantined failed request")
+	return nil
+}
+
+// Close implements Sink.
+func (s *PolicySink) Close() error {
+	err := s.next.Close()
+	if s.deadLetter != nil {
+		if derr := s.deadLetter.Close(); err == nil {
+			err = derr
+		}
+	}
+	if s.failed > 0 {
+		l := log.WithField("amount", s.failed)
+		if s.deadLetter != nil {
+			l = l.WithField("path", s.deadLetter.file.Name())
+		}
+		l.Warning("some requests failed")
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package writer
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathRetryMaxAttempts = "writer.retry.maxattempts"
+	PathRetryBase        = "writer.retry.base"
+	PathRetryCap         = "writer.retry.cap"
+	PathRetryJitter      = "writer.retry.jitter"
+	PathRetryStatuses    = "writer.retry.statuses"
+	PathRetryAfter       = "writer.retry.retryafter"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().Uint(PathRetryMaxAttempts, 5, "the maximum number of failed attempts per request, not counting status 226 (openefs busy), which is retried without limit (unlimited if 0)")
+	config.Viper.BindPFlag(PathRetryMaxAttempts, config.RootCtx.PersistentFlags().Lookup(PathRetryMaxAttempts))
+
+	config.RootCtx.PersistentFlags().Duration(PathRetryBase, time.Second, "the delay before the first retry (doubled on every further retry) and between retries while openefs is busy")
+	config.Viper.BindPFlag(PathRetryBase, config.RootCtx.PersistentFlags().Lookup(PathRetryBase))
+
+	config.RootCtx.PersistentFlags().Duration(PathRetryCap, 30*time.Second, "the maximum delay between two retries")
+	config.Viper.BindPFlag(PathRetryCap, config.RootCtx.PersistentFlags().Lookup(PathRetryCap))
+
+	config.RootCtx.PersistentFlags().Float64(PathRetryJitter, 0.2, "the fraction (0 to 1) of each delay, that is randomized")
+	config.Viper.BindPFlag(PathRetryJitter, config.RootCtx.PersistentFlags().Lookup(PathRetryJitter))
+
+	config.RootCtx.PersistentFlags().IntSlice(PathRetryStatuses, []int{http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout}, "the response-statuses, that cause a request to be retried (network-errors are always retried, as is status 226 (openefs busy), which is retried every "+PathRetryBase+")")
+	config.Viper.BindPFlag(PathRetryStatuses, config.RootCtx.PersistentFlags().Lookup(PathRetryStatuses))
+
+	config.RootCtx.PersistentFlags().Bool(PathRetryAfter, true, "respect the Retry-After header (still limited by "+PathRetryCap+")")
+	config.Viper.BindPFlag(PathRetryAfter, config.RootCtx.PersistentFlags().Lookup(PathRetryAfter))
+}
+
+// RetryPolicy decides whether and when a failed request is repeated.
+type RetryPolicy struct {
+	// MaxAttempts limits the number of failed attempts per request. Zero
+	// means unlimited.
+	MaxAttempts uint
+	// Base is the delay before the first retry. It is doubled with each
+	// further retry up to Cap.
+	Base time.Duration
+	Cap  time.Duration
+	// Jitter is the fraction of each delay, that is randomized.
+	Jitter float64
+	// Statuses are the response-statuses worth a retry. http.StatusIMUsed is
+	// always retried.
+	Statuses []int
+	// RetryAfter enables respecting the Retry-After header.
+	RetryAfter bool
+}
+
+func NewRetryPolicyFromConfig() *RetryPolicy {
+	jitter := config.Viper.GetFloat64(PathRetryJitter)
+	if jitter < 0 || jitter > 1 {
+		config.InvalidConfiguration(PathRetryJitter, "a fraction between 0 and 1")
+	}
+	return &RetryPolicy{
+		MaxAttempts: config.Viper.GetUint(PathRetryMaxAttempts),
+		Base:        config.Viper.GetDuration(PathRetryBase),
+		Cap:         config.Viper.GetDuration(PathRetryCap),
+		Jitter:      jitter,
+		Statuses:    config.Viper.GetIntSlice(PathRetryStatuses),
+		RetryAfter:  config.Viper.GetBool(PathRetryAfter),
+	}
+}
+
+// exhausted returns whether no further attempt may follow the given number of
+// failed attempts.
+func (p *RetryPolicy) exhausted(failures uint) bool {
+	return p.MaxAttempts != 0 && failures >= p.MaxAttempts
+}
+
+func (p *RetryPolicy) retryable(status int) bool {
+	for _, s := range p.Statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// delay returns the duration to wait after the given number of failed
+// attempts. resp may be nil.
+func (p *RetryPolicy) delay(failures uint, resp *http.Response) time.Duration {
+	if p.RetryAfter && resp != nil {
+		if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+			if d > p.Cap {
+				return p.Cap
+			}
+			return d
+		}
+	}
+
+	d := p.Base
+	for i := uint(1); i < failures && d < p.Cap; i++ {
+		d *= 2
+	}
+	if d > p.Cap {
+		d = p.Cap
+	}
+	return d - time.Duration(p.Jitter*rand.Float64()*float64(d))
+}
+
+// retryAfter parses the Retry-After header, which holds either seconds or an
+// HTTP-date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.ParseUint(value, 10, 32); err == nil {
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
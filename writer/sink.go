@@ -0,0 +1,167 @@
+package writer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Kinds of requests
+const (
+	KindProduction = "production"
+	KindWeather    = "weather"
+	KindMockTime   = "mocktime"
+)
+
+// Request is a single request to be sent to openefs.
+type Request struct {
+	Kind      string          `json:"kind"`
+	Timestamp int64           `json:"timestamp"`
+	Method    string          `json:"method"`
+	Endpoint  string          `json:"endpoint"`
+	Body      json.RawMessage `json:"body,omitempty"`
+	Batch     bool            `json:"batch,omitempty"`
+}
+
+// Time returns the Request's Timestamp.
+func (r *Request) Time() time.Time {
+	return time.Unix(r.Timestamp, 0)
+}
+
+// Sink is where a Writer's requests end up.
+type Sink interface {
+	Send(r *Request) error
+	Close() error
+}
+
+// Flusher is implemented by Sinks, that buffer requests.
+type Flusher interface {
+	Flush() error
+}
+
+// Flush sends all requests buffered by s, if s is a Flusher.
+func Flush(s Sink) error {
+	if f, ok := s.(Flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
+
+// Entry is a single line of the files written by FileSink.
+type Entry struct {
+	Recorded time.Time `json:"recorded"`
+	*Request
+}
+
+// FileSink writes all requests to a JSONL-file instead of sending them.
+type FileSink struct {
+	file *os.File
+	enc  *json.Encoder
+	m    sync.Mutex
+}
+
+// NewFileSink creates (or truncates) the file at path.
+func NewFileSink(path string) (*FileSink, error) {
+	return newFileSink(path, os.O_TRUNC)
+}
+
+// NewAppendingFileSink creates the file at path or appends to it, if it
+// already exists.
+func NewAppendingFileSink(path string) (*FileSink, error) {
+	return newFileSink(path, os.O_APPEND)
+}
+
+func newFileSink(path string, flag int) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|flag, 0666)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{
+		file: f,
+		enc:  json.NewEncoder(f),
+	}, nil
+}
+
+// Send implements Sink.
+func (s *FileSink) Send(r *Request) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	log.WithField("kind", r.Kind).WithField("endpoint", r.Endpoint).Trace("recording request...")
+	return s.enc.Encode(Entry{
+		Recorded: time.Now(),
+		Request:  r,
+	})
+}
+
+// Close implements Sink.
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// RecordingSink records all requests to a JSONL-file before passing them on.
+type RecordingSink struct {
+	next     Sink
+	recorder *FileSink
+}
+
+// NewRecordingSink creates (or truncates) the file at path.
+func NewRecordingSink(next Sink, path string) (*RecordingSink, error) {
+	recorder, err := NewFileSink(path)
+	if err != nil {
+		return nil, err
+	}
+	return &RecordingSink{
+		next:     next,
+		recorder: recorder,
+	}, nil
+}
+
+// Send implements Sink.
+func (s *RecordingSink) Send(r *Request) error {
+	if err := s.recorder.Send(r); err != nil {
+		return err
+	}
+	return s.next.Send(r)
+}
+
+// Flush implements Flusher.
+func (s *RecordingSink) Flush() error {
+	return Flush(s.next)
+}
+
+// Close implements Sink.
+func (s *RecordingSink) Close() error {
+	err := s.next.Close()
+	if rerr := s.recorder.Close(); err == nil {
+		err = rerr
+	}
+	return err
+}
+
+// ReadEntries calls fn for every Entry in the JSONL-file at path, in order.
+func ReadEntries(path string, fn func(*Entry) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(f))
+	for {
+		e := &Entry{}
+		if err := dec.Decode(e); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if e.Request == nil {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package writer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+
+	"github.com/theMomax/openefs-csv-feeder/config"
+)
+
+// Config paths
+const (
+	PathTLSCA                 = "writer.tls.ca"
+	PathTLSCert               = "writer.tls.cert"
+	PathTLSKey                = "writer.tls.key"
+	PathTLSInsecureSkipVerify = "writer.tls.insecureskipverify"
+)
+
+func init() {
+	config.RootCtx.PersistentFlags().String(PathTLSCA, "", "path to a PEM-encoded CA bundle used to verify openefs' certificate (the system's pool is used if empty)")
+	config.Viper.BindPFlag(PathTLSCA, config.RootCtx.PersistentFlags().Lookup(PathTLSCA))
+
+	config.RootCtx.PersistentFlags().String(PathTLSCert, "", "path to a PEM-encoded client-certificate for mutual TLS")
+	config.Viper.BindPFlag(PathTLSCert, config.RootCtx.PersistentFlags().Lookup(PathTLSCert))
+
+	config.RootCtx.PersistentFlags().String(PathTLSKey, "", "path to the PEM-encoded private key belonging to "+PathTLSCert)
+	config.Viper.BindPFlag(PathTLSKey, config.RootCtx.PersistentFlags().Lookup(PathTLSKey))
+
+	config.RootCtx.PersistentFlags().Bool(PathTLSInsecureSkipVerify, false, "do not verify openefs' certificate (for lab setups only)")
+	config.Viper.BindPFlag(PathTLSInsecureSkipVerify, config.RootCtx.PersistentFlags().Lookup(PathTLSInsecureSkipVerify))
+}
+
+// NewTLSConfig returns the TLS configuration used for talking to openefs. All
+// paths are optional, however cert and key must be given together.
+func NewTLSConfig(ca, cert, key string, insecureSkipVerify bool) (*tls.Config, error) {
+	c := &tls.Config{
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	if ca != "" {
+		pem, err := ioutil.ReadFile(ca)
+		if err != nil {
+			return nil, err
+		}
+		c.RootCAs = x509.NewCertPool()
+		if !c.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in CA bundle '" + ca + "'")
+		}
+	}
+
+	if (cert == "") != (key == "") {
+		return nil, errors.New(PathTLSCert + " and " + PathTLSKey + " must be given together")
+	}
+	if cert != "" {
+		pair, err := tls.LoadX509KeyPair(cert, key)
+		if err != nil {
+			return nil, err
+		}
+		c.Certificates = []tls.Certificate{pair}
+	}
+	return c, nil
+}
+
+// NewTLSConfigFromConfig calls NewTLSConfig using the values configured by
+// this package's config paths.
+func NewTLSConfigFromConfig() (*tls.Config, error) {
+	insecure := config.Viper.GetBool(PathTLSInsecureSkipVerify)
+	if insecure {
+		log.Warning("certificate verification is disabled")
+	}
+	return NewTLSConfig(config.Viper.GetString(PathTLSCA), config.Viper.GetString(PathTLSCert), config.Viper.GetString(PathTLSKey), insecure)
+}
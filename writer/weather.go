@@ -1,12 +1,9 @@
 package writer
 
 import (
-	"bytes"
 	"encoding/json"
-	"errors"
 	"net/http"
-	"strconv"
-	"strings"
+	"sync"
 	"time"
 
 	models "github.com/theMomax/openefs/models/production/weather"
@@ -18,21 +15,53 @@ func (w *Writer) WriteWeather(date time.Time, data *models.Data) error {
 		return err
 	}
 
-	for {
-		log.WithField("data", data).WithField("date", date).Trace("trying to send weather-data...")
-		resp, err := http.Post(strings.ReplaceAll(w.weatheraddress, ":unixtimestamp", strconv.FormatInt(date.Unix(), 10)), "application/json", bytes.NewReader(b))
-		if err != nil {
-			return err
-		}
+	log.WithField("data", data).WithField("date", date).Trace("trying to send weather-data...")
+	err = w.sink.Send(&Request{
+		Kind:      KindWeather,
+		Timestamp: date.Unix(),
+		Method:    http.MethodPost,
+		Endpoint:  w.Endpoint(KindWeather, date),
+		Body:      b,
+	})
+	if err != nil {
+		return err
+	}
+	log.WithField("data", data).WithField("date", date).Debug("succesfully sent weather-data")
+	return nil
+}
+
+// WriteWeatherForecast writes all given weather-data using up to the Writer's
+// concurrency in parallel. It returns once all requests have completed. No
+// further requests are started after the first failure, which is returned.
+func (w *Writer) WriteWeatherForecast(dates []time.Time, data []*models.Data) error {
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	var first error
 
-		switch resp.StatusCode {
-		case http.StatusOK:
-			log.WithField("data", data).WithField("date", date).Debug("succesfully sent weather-data")
-			return nil
-		case http.StatusIMUsed:
-			time.Sleep(time.Second)
-		default:
-			return errors.New(resp.Status)
+	for i := range dates {
+		w.slots <- struct{}{}
+		m.Lock()
+		failed := first != nil
+		m.Unlock()
+		if failed {
+			<-w.slots
+			break
 		}
+
+		wg.Add(1)
+		go func(date time.Time, data *models.Data) {
+			defer wg.Done()
+			defer func() { <-w.slots }()
+			if err := w.WriteWeather(date, data); err != nil {
+				m.Lock()
+				if first == nil {
+					first = err
+				}
+				m.Unlock()
+			}
+		}(dates[i], data[i])
 	}
+
+	wg.Wait()
+	return first
 }